@@ -0,0 +1,290 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/clipperhouse/typewriter"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// add adds a new typewriter import to the current package, by creating (or appending) a _gen.go file.
+//
+// Arguments are import paths, optionally with a version, such as github.com/clipperhouse/foowriter@v1.2.0.
+// In module mode, the resolved version is pinned in _gen.go, and the user's go.mod is left alone.
+//
+// Failures are reported as an *addError.
+func add(c Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please specify the import path of the typewriter you wish to add")
+	}
+
+	paths := strings.Join(args, " ")
+
+	imports, err := getTypewriterImports(c)
+
+	if err != nil {
+		return newAddError(paths, err, fmt.Sprintf("fix the syntax error in %s, or remove it", c.customName))
+	}
+
+	versions, err := getTypewriterVersions(c)
+
+	if err != nil {
+		return newAddError(paths, err, fmt.Sprintf("fix the syntax error in %s, or remove it", c.customName))
+	}
+
+	modules, err := moduleMode(c)
+
+	if err != nil {
+		return newAddError(paths, err, "check that the go tool is installed and on your PATH")
+	}
+
+	for _, arg := range args {
+		path, version := arg, ""
+		if i := strings.LastIndex(arg, "@"); i >= 0 {
+			path, version = arg[:i], arg[i+1:]
+		}
+
+		imp := typewriter.ImportSpec{Name: "_", Path: path}
+
+		if modules {
+			v, err := resolve(c, path, version)
+
+			if err != nil {
+				remedy := "check the import path, and that it can be fetched by go get (see GOPROXY and GOPRIVATE)"
+				if len(version) > 0 {
+					remedy = fmt.Sprintf("check that version %s of %s exists, or omit the version for the latest", version, path)
+				}
+				return newAddError(path, err, remedy)
+			}
+
+			versions[path] = v
+		} else {
+			if len(version) > 0 {
+				return newAddError(path, fmt.Errorf("versions are only supported in module mode"), "omit the version, or use modules (see go help modules)")
+			}
+
+			// try to go get it
+			if err := c.goRun("", nil, "get", imp.Path); err != nil {
+				return newAddError(path, err, "check the import path, and that it can be fetched by go get")
+			}
+		}
+
+		imports.Add(imp)
+	}
+
+	if err := writeCustomFile(c, imports, versions); err != nil {
+		return newAddError(paths, err, fmt.Sprintf("check that %s can be written, and parses", c.customName))
+	}
+
+	return nil
+}
+
+// addError describes a failure to add a typewriter
+type addError struct {
+	// Path is the import path (or paths) being added
+	Path string
+	Err  error
+	// Output is the standard error of a failed go command, if any
+	Output string
+	// Remedy suggests how to fix the problem
+	Remedy string
+}
+
+// newAddError returns an addError, with the output of the go tool if err is a toolError
+func newAddError(path string, err error, remedy string) *addError {
+	e := &addError{
+		Path:   path,
+		Err:    err,
+		Remedy: remedy,
+	}
+
+	var te *toolError
+	if errors.As(err, &te) {
+		e.Err = te.Err
+		e.Output = strings.TrimSpace(te.Stderr)
+	}
+
+	return e
+}
+
+func (e *addError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "add %s: %v", e.Path, e.Err)
+
+	if len(e.Output) > 0 {
+		b.WriteString("\n\t" + strings.Replace(e.Output, "\n", "\n\t", -1))
+	}
+
+	if len(e.Remedy) > 0 {
+		b.WriteString("\n" + e.Remedy)
+	}
+
+	return b.String()
+}
+
+func (e *addError) Unwrap() error {
+	return e.Err
+}
+
+func createCustomFile(c Config, imports typewriter.ImportSpecSet, versions map[string]string) error {
+	w, err := os.Create(c.customName)
+
+	if err != nil {
+		return err
+	}
+
+	defer w.Close()
+
+	p := pkg{
+		Name:     "main",
+		Imports:  imports,
+		Versions: versions,
+	}
+
+	if err := tmpl.Execute(w, p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeCustomFile updates the custom file to import exactly imports, pinned to versions. An existing file is edited in
+// place (see editCustomFile), preserving its comments, build tags and other code; otherwise it is created.
+func writeCustomFile(c Config, imports typewriter.ImportSpecSet, versions map[string]string) error {
+	src, err := ioutil.ReadFile(c.customName)
+
+	if os.IsNotExist(err) {
+		return createCustomFile(c, imports, versions)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	edited, err := editCustomFile(src, imports, versions)
+
+	if err != nil {
+		return fmt.Errorf("%s: %v; not rewriting it", c.customName, err)
+	}
+
+	return ioutil.WriteFile(c.customName, edited, 0666)
+}
+
+// editCustomFile adds and removes import specs in src, so that it imports exactly imports, and updates their pinned
+// versions (see parseImports). Everything else in src is preserved. It is an error if src does not parse.
+func editCustomFile(src []byte, imports typewriter.ImportSpecSet, versions map[string]string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	existing := typewriter.NewImportSpecSet()
+
+	for _, spec := range importSpecs(f) {
+		if !imports.Contains(spec) {
+			astutil.DeleteNamedImport(fset, f, spec.Name, spec.Path)
+			continue
+		}
+		existing.Add(spec)
+	}
+
+	for _, imp := range (pkg{Imports: imports}).SortedImports() {
+		if !existing.Contains(imp) {
+			astutil.AddNamedImport(fset, f, imp.Name, imp.Path)
+		}
+	}
+
+	var b bytes.Buffer
+
+	if err := gofmt.Node(&b, fset, f); err != nil {
+		return nil, err
+	}
+
+	// version comments are edited as text, as comments are not attached to nodes in the AST;
+	// re-parse to get positions
+	out := b.Bytes()
+	fset = token.NewFileSet()
+	f, err = parser.ParseFile(fset, "", out, parser.ParseComments|parser.ImportsOnly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+
+	var edits []edit
+
+	for _, spec := range f.Imports {
+		path := strings.Trim(spec.Path.Value, `"`)
+		want := versions[path]
+		have, pinned := pinnedVersion(spec.Comment)
+
+		if have == want {
+			continue
+		}
+
+		if !pinned {
+			// insert ahead of any existing comment, which then follows the version
+			end := fset.Position(spec.End()).Offset
+			edits = append(edits, edit{end, end, " // " + want})
+			continue
+		}
+
+		comment := spec.Comment.List[0]
+		start, end := fset.Position(comment.Pos()).Offset, fset.Position(comment.End()).Offset
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(comment.Text, "//")), have))
+
+		switch {
+		case len(want) > 0 && len(rest) > 0:
+			edits = append(edits, edit{start, end, "// " + want + " " + rest})
+		case len(want) > 0:
+			edits = append(edits, edit{start, end, "// " + want})
+		case len(rest) > 0:
+			edits = append(edits, edit{start, end, "// " + rest})
+		default:
+			edits = append(edits, edit{start, end, ""})
+		}
+	}
+
+	// apply from the end, so that earlier offsets remain valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+
+	return gofmt.Source(out)
+}
+
+// importSpecs returns the imports of f as ImportSpecs
+func importSpecs(f *ast.File) []typewriter.ImportSpec {
+	var specs []typewriter.ImportSpec
+
+	for _, v := range f.Imports {
+		spec := typewriter.ImportSpec{
+			Path: strings.Trim(v.Path.Value, `"`),
+		}
+
+		if v.Name != nil {
+			spec.Name = v.Name.Name
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
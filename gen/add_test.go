@@ -0,0 +1,295 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestAdd(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_add_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var b bytes.Buffer
+	tool := newFakeModule()
+
+	c := DefaultConfig
+	c.out = &b
+	c.tool = tool
+	c.customName = filepath.Join(root, "_gen.go")
+
+	if err := add(c); err == nil {
+		t.Error("add with no arguments should be an error")
+	}
+
+	foo := typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/foowriter"}
+	before, err := getTypewriterImports(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	// ensure that the custom import is not in the default set
+	if before.Contains(foo) {
+		t.Errorf("default imports should not include %s", foo.Path)
+	}
+
+	// adding import which exists should succeed
+	if err := add(c, foo.Path+"@v1.2"); err != nil {
+		t.Error(err)
+	}
+
+	if !tool.called("go get " + foo.Path + "@v1.2") {
+		t.Errorf("add should go get %s, got %v", foo.Path, tool.calls)
+	}
+
+	after, err := getTypewriterImports(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the new import should be reflected in imports
+	if !after.Contains(foo) {
+		t.Errorf("imports should include %s", foo.Path)
+	}
+
+	// at the resolved version
+	versions, err := getTypewriterVersions(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if versions[foo.Path] != "v1.2.3" {
+		t.Errorf("%s should be pinned at v1.2.3, got %v", foo.Path, versions)
+	}
+}
+
+func TestEditCustomFile(t *testing.T) {
+	src := `// +build gen
+
+// Package main lists our typewriters.
+package main
+
+import (
+	// the standard ones
+	_ "github.com/clipperhouse/slice" // v1.0.0 keep this
+	_ "github.com/clipperhouse/stringer"
+
+	foo "github.com/clipperhouse/foowriter" // v0.1.0
+)
+
+// used by foo
+var _ = foo.Thing
+`
+
+	imports := typewriter.NewImportSpecSet(
+		typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/slice"},
+		typewriter.ImportSpec{Name: "foo", Path: "github.com/clipperhouse/foowriter"},
+		typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/setwriter"},
+	)
+
+	versions := map[string]string{
+		"github.com/clipperhouse/slice":     "v1.1.0",
+		"github.com/clipperhouse/setwriter": "v2.0.0",
+	}
+
+	edited, err := editCustomFile([]byte(src), imports, versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ignore alignment
+	got := regexp.MustCompile(`[ \t]+`).ReplaceAllString(string(edited), " ")
+
+	for _, s := range []string{
+		"// +build gen\n",
+		"// Package main lists our typewriters.\n",
+		" // the standard ones\n",
+		`_ "github.com/clipperhouse/slice" // v1.1.0 keep this` + "\n",
+		`foo "github.com/clipperhouse/foowriter"` + "\n",
+		`_ "github.com/clipperhouse/setwriter" // v2.0.0` + "\n",
+		"// used by foo\nvar _ = foo.Thing\n",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("edited file should contain %q, got:\n%s", s, got)
+		}
+	}
+
+	if strings.Contains(got, "stringer") {
+		t.Errorf("edited file should not import stringer, got:\n%s", got)
+	}
+
+	// round trip
+	after, pins, err := parseImports(edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !after.Equal(imports) {
+		t.Errorf("edited imports should be %v, got %v", imports, after)
+	}
+
+	if len(pins) != 2 || pins["github.com/clipperhouse/slice"] != "v1.1.0" || pins["github.com/clipperhouse/setwriter"] != "v2.0.0" {
+		t.Errorf("edited versions should be %v, got %v", versions, pins)
+	}
+
+	// refuse to rewrite a file which doesn't parse
+	if _, err := editCustomFile([]byte("package main\n\nimport _ \"github.com/clipperhouse/slice\"\n\nfunc {\n"), imports, versions); err == nil {
+		t.Error("editing a file which doesn't parse should be an error")
+	}
+}
+
+// fakeGo puts a fake go tool first on PATH, for the duration of a test. Its behavior is set by environment:
+// FAKE_GOMOD is printed by go env, and FAKE_GO_FAIL names a subcommand which fails.
+func fakeGo(t *testing.T) func() {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go tool is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "gen_fake_go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := `#!/bin/sh
+if [ "$FAKE_GO_FAIL" = "$1" ]; then
+	echo "fake go $1: cannot find $2" >&2
+	exit 1
+fi
+case "$1" in
+env) echo "$FAKE_GOMOD" ;;
+get) echo "go: added $2" >&2 ;;
+list) echo v1.2.3 ;;
+esac
+`
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	return func() {
+		os.Setenv("PATH", path)
+		os.Unsetenv("FAKE_GOMOD")
+		os.Unsetenv("FAKE_GO_FAIL")
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	defer fakeGo(t)()
+
+	root, err := ioutil.TempDir("", "gen_add_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.customName = filepath.Join(root, "_gen.go")
+
+	foo := "github.com/clipperhouse/foowriter"
+
+	tests := []struct {
+		name, gomod, fail string
+		arg               string
+		custom            string // existing custom file, if any
+		output, remedy    string // expected in the error
+	}{
+		{"go env", "", "env", foo, "", "fake go env", "go tool is installed"},
+		{"go get", "", "get", foo, "", "fake go get: cannot find " + foo, "check the import path"},
+		{"version without modules", "", "", foo + "@v1.0.0", "", "", "omit the version"},
+		{"resolve", "/src/go.mod", "get", foo + "@v1.0.0", "", "fake go get: cannot find " + foo + "@v1.0.0", "check that version v1.0.0"},
+		{"unparseable", "/src/go.mod", "", foo, "package main\n\nfunc {\n", "", "can be written, and parses"},
+	}
+
+	for _, test := range tests {
+		os.Setenv("FAKE_GOMOD", test.gomod)
+		os.Setenv("FAKE_GO_FAIL", test.fail)
+		os.Remove(c.customName)
+
+		if len(test.custom) > 0 {
+			if err := ioutil.WriteFile(c.customName, []byte(test.custom), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := add(c, test.arg)
+
+		ae, ok := err.(*addError)
+		if !ok {
+			t.Errorf("%s: add should return an *addError, got %v", test.name, err)
+			continue
+		}
+
+		if !strings.HasPrefix(test.arg, ae.Path) {
+			t.Errorf("%s: error should include the import path %s, got %s", test.name, test.arg, ae.Path)
+		}
+
+		if !strings.Contains(ae.Output, test.output) {
+			t.Errorf("%s: error should include the go tool output %q, got %q", test.name, test.output, ae.Output)
+		}
+
+		if !strings.Contains(ae.Remedy, test.remedy) {
+			t.Errorf("%s: error should suggest %q, got %q", test.name, test.remedy, ae.Remedy)
+		}
+
+		if exitStatusMsg.MatchString(err.Error()) {
+			t.Errorf("%s: error should not be hidden by main, got %q", test.name, err)
+		}
+
+		// nothing written
+		if len(test.custom) == 0 {
+			if _, err := os.Stat(c.customName); err == nil {
+				t.Errorf("%s: add should not write %s on failure", test.name, c.customName)
+			}
+		}
+	}
+
+	// a failure to write the custom file is reported
+	os.Setenv("FAKE_GOMOD", "/src/go.mod")
+	os.Setenv("FAKE_GO_FAIL", "")
+
+	c.customName = filepath.Join(root, "missing", "_gen.go")
+
+	if err := add(c, foo); err == nil {
+		t.Error("add should fail when the custom file cannot be written")
+	}
+
+	// and success, pinned at the version reported by go list
+	c.customName = filepath.Join(root, "_gen.go")
+	os.Remove(c.customName)
+
+	if err := add(c, foo); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := getTypewriterVersions(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if versions[foo] != "v1.2.3" {
+		t.Errorf("add should pin %s at v1.2.3, got %v", foo, versions)
+	}
+
+	if !strings.Contains(b.String(), "go: added "+foo+"@latest") {
+		t.Errorf("add should pass on the output of go get, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,138 @@
+package gen
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options are the options of Run. Zero values are as for the gen command.
+type Options struct {
+	// Dir is the directory in which to run, by default the working directory
+	Dir string
+	// Patterns are package patterns relative to Dir, such as ./...; by default, Dir only
+	Patterns []string
+	// IgnoreTypeCheckErrors is the equivalent of the -f flag
+	IgnoreTypeCheckErrors bool
+	// DryRun generates files without writing them
+	DryRun bool
+	// Prune removes orphaned generated files, without asking
+	Prune bool
+	// Out receives the output of gen, typewriters and the go tool, which is otherwise discarded
+	Out io.Writer
+}
+
+// Result describes the outcome of Run
+type Result struct {
+	// Written are the paths of generated files written
+	Written []string
+	// Removed are the paths of orphaned generated files removed
+	Removed []string
+	// Warnings are problems which did not cause Run to fail, such as orphaned files not removed
+	Warnings []string
+	// Timings are the time taken by each package, keyed by directory as given by Patterns ("." for Dir alone)
+	Timings map[string]time.Duration
+	// Duration is the time taken by Run
+	Duration time.Duration
+}
+
+// Run generates files for types marked with +gen, as the gen command does. The project configuration file (gen.toml
+// or gen.json) applies, as found from Dir. Packages are not started once ctx is done.
+//
+// Run changes the working directory of the process to Dir, and to each package, while it runs. Calls of Run are
+// serialized, but other goroutines of the program must not depend on the working directory meanwhile, such as by
+// opening files by relative paths.
+func Run(ctx context.Context, opts Options) (Result, error) {
+	chdirMu.Lock()
+	defer chdirMu.Unlock()
+
+	start := time.Now()
+
+	result := Result{
+		Timings: make(map[string]time.Duration),
+	}
+
+	c := DefaultConfig
+
+	// don't mutate the default
+	conf := *c.Config
+	c.Config = &conf
+
+	c.out = ioutil.Discard
+	if opts.Out != nil {
+		c.out = opts.Out
+	}
+
+	// not a terminal, so prune can't ask; see Options.Prune
+	c.in = strings.NewReader("")
+	c.ctx = ctx
+	c.result = &result
+
+	dir := opts.Dir
+	if len(dir) == 0 {
+		dir = "."
+	}
+
+	err := inDir(dir, func() error {
+		if err := loadConfigFile(&c); err != nil {
+			return err
+		}
+
+		if opts.IgnoreTypeCheckErrors {
+			c.IgnoreTypeCheckErrors = true
+		}
+		c.dryRun = opts.DryRun
+		c.prune = opts.Prune
+		c.yes = opts.Prune
+
+		return forPatterns(c, opts.Patterns, run)
+	})
+
+	result.Duration = time.Since(start)
+
+	return result, err
+}
+
+// wrote records a written file, for Result
+func (c Config) wrote(name string) {
+	if c.result != nil {
+		c.result.Written = append(c.result.Written, abs(name))
+	}
+}
+
+// removed records a removed file, for Result
+func (c Config) removed(name string) {
+	if c.result != nil {
+		c.result.Removed = append(c.result.Removed, abs(name))
+	}
+}
+
+// warn records a warning, for Result; printing it is up to the caller
+func (c Config) warn(msg string) {
+	if c.result != nil {
+		c.result.Warnings = append(c.result.Warnings, msg)
+	}
+}
+
+// timed calls fn, recording the time taken in dir, for Result
+func (c Config) timed(dir string, fn func(c Config) error) error {
+	start := time.Now()
+	err := fn(c)
+
+	if c.result != nil {
+		c.result.Timings[dir] += time.Since(start)
+	}
+
+	return err
+}
+
+// abs returns the absolute path of name, or name if that fails
+func abs(name string) string {
+	if path, err := filepath.Abs(name); err == nil {
+		return path
+	}
+	return name
+}
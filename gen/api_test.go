@@ -0,0 +1,121 @@
+package gen
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunLibrary(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_api_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// for comparison with absolute paths
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTree(t, root, map[string]string{
+		"a/a.go":   "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+		"a/b/b.go": "package b\n\n// +gen slice:\"Any\"\ntype B int\n",
+	})
+
+	ctx := context.Background()
+	opts := Options{
+		Dir:      root,
+		Patterns: []string{"./..."},
+	}
+
+	result, err := Run(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(root, "a", "a_slice.go"), filepath.Join(root, "a", "b", "b_slice.go")}
+
+	if strings.Join(result.Written, " ") != strings.Join(expected, " ") {
+		t.Errorf("Run should write %v, got %v", expected, result.Written)
+	}
+
+	if len(result.Timings) != 2 || result.Duration <= 0 {
+		t.Errorf("Run should time 2 packages, got %v in %v", result.Timings, result.Duration)
+	}
+
+	// the files are as the gen command writes them, whatever the binary calling Run
+	src, err := ioutil.ReadFile(expected[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(src), "// Generated by: gen\n") {
+		t.Errorf("Run should write a byline naming gen, got:\n%s", src)
+	}
+
+	c := DefaultConfig
+	c.out = ioutil.Discard
+
+	if err := forPackages(c, []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b")}, check); err != nil {
+		t.Errorf("check should succeed after Run, got %v", err)
+	}
+
+	// the working directory is restored
+	if wd, _ := os.Getwd(); strings.HasPrefix(wd, root) {
+		t.Errorf("Run should restore the working directory, got %s", wd)
+	}
+
+	// orphans are warned of, or removed
+	writeTree(t, root, map[string]string{
+		"a/b/b.go": "package b\n\n// +gen slice:\"Any\"\ntype B int\n\n// +gen slice:\"Any\"\ntype C int\n",
+	})
+
+	if err := os.Rename(filepath.Join(root, "a", "b", "b_slice.go"), filepath.Join(root, "a", "b", "x_slice.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.DryRun = true
+
+	result, err = Run(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Written) != 0 {
+		t.Errorf("Run should not write with DryRun, got %v", result.Written)
+	}
+
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "x_slice.go") {
+		t.Errorf("Run should warn of orphaned x_slice.go, got %v", result.Warnings)
+	}
+
+	opts.DryRun = false
+	opts.Prune = true
+
+	result, err = Run(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Removed) != 1 || result.Removed[0] != filepath.Join(root, "a", "b", "x_slice.go") {
+		t.Errorf("Run should remove x_slice.go, got %v", result.Removed)
+	}
+
+	// no packages are started once cancelled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	result, err = Run(cancelled, opts)
+	if err != context.Canceled {
+		t.Errorf("Run should return context.Canceled, got %v", err)
+	}
+
+	if len(result.Timings) != 0 {
+		t.Errorf("Run should not run any package once cancelled, got %v", result.Timings)
+	}
+}
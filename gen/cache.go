@@ -0,0 +1,200 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// cache prints the location of the runner cache or, with the clean argument, removes it
+func cache(c Config, args ...string) error {
+	dir, err := c.cache()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(c.out, dir)
+		return nil
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.out, "Removed %s\n", dir)
+	return nil
+}
+
+// cache returns the directory in which compiled custom runners are kept
+func (c Config) cache() (string, error) {
+	if len(c.cacheDir) > 0 {
+		return c.cacheDir, nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "gen"), nil
+}
+
+// runner returns the path to a compiled program for the custom imports and main source, building it if not already cached.
+//
+// Runners are keyed by a hash of their source, and the versions (or, lacking versions, the source) of the packages they
+// depend on (see cacheKey).
+func runner(c Config, custom, main []byte) (string, error) {
+	dir, err := c.cache()
+	if err != nil {
+		return "", err
+	}
+
+	deps, err := runnerModules(c, custom)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := cacheKey(c, custom, main, deps)
+	if err != nil {
+		return "", err
+	}
+
+	// name the runner gen, as it appears in the byline of generated files
+	name := "gen"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	exe := filepath.Join(dir, key, name)
+
+	if _, err := os.Stat(exe); err == nil {
+		return exe, nil
+	}
+
+	if err := build(c, custom, main, deps, exe); err != nil {
+		return "", err
+	}
+
+	return exe, nil
+}
+
+// build compiles the custom imports and main source into exe.
+//
+// The build happens in a temp directory outside of the user's package; in module mode, it is
+// a module of its own, requiring the modules of deps (see goMod), and then getting its gets (see runnerModules).
+func build(c Config, custom, main []byte, deps runnerDeps, exe string) error {
+	temp, err := getTempDir()
+	if err != nil {
+		return err
+	}
+	defer removeTempDir(temp)
+
+	if err := ioutil.WriteFile(filepath.Join(temp, "imports.go"), custom, 0666); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(temp, "main.go"), main, 0666); err != nil {
+		return err
+	}
+
+	modules := len(deps.mods) > 0 || len(deps.gets) > 0
+
+	if modules {
+		if err := writeModule(c, temp, deps.mods); err != nil {
+			return err
+		}
+	}
+
+	var env []string
+
+	if modules {
+		// the user's -mod=vendor, for example, does not apply to the runner's module
+		env = []string{"GOFLAGS=-mod=mod"}
+	}
+
+	if len(deps.gets) > 0 {
+		if err := c.tool.Go(temp, env, c.out, c.out, append([]string{"get"}, deps.gets...)...); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(exe), 0777); err != nil {
+		return err
+	}
+
+	// build alongside, then rename, so that a concurrent gen never sees a partial runner
+	partial := exe + ".partial" + filepath.Base(temp)
+	defer os.Remove(partial)
+
+	if err := c.tool.Go(temp, env, c.out, c.out, "build", "-o", partial, "."); err != nil {
+		return err
+	}
+
+	return os.Rename(partial, exe)
+}
+
+// cacheKey hashes the runner source, the go version, the versions of the modules providing the custom imports
+// (and their dependencies) and, as they may change without a change of version, the source of packages not
+// provided by a module version (see runnerDeps)
+func cacheKey(c Config, custom, main []byte, deps runnerDeps) (string, error) {
+	h := sha256.New()
+	h.Write(custom)
+	h.Write(main)
+
+	version, err := c.goVersion()
+	if err != nil {
+		return "", err
+	}
+	h.Write(version)
+
+	h.Write(goMod(deps.mods))
+
+	for _, dir := range deps.dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return "", err
+		}
+
+		for _, fi := range infos {
+			if !fi.Mode().IsRegular() || strings.HasSuffix(fi.Name(), "_test.go") {
+				continue
+			}
+
+			src, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "%s %d\n", filepath.Join(dir, fi.Name()), len(src))
+			h.Write(src)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// goVersions memoizes the output of go version by toolchain, for cacheKey
+var goVersions sync.Map
+
+// goVersion returns the output of go version, once per process for each toolchain
+func (c Config) goVersion() ([]byte, error) {
+	if v, ok := goVersions.Load(c.tool); ok {
+		return v.([]byte), nil
+	}
+
+	version, err := c.goOutput("version")
+	if err != nil {
+		return nil, err
+	}
+
+	goVersions.Store(c.tool, version)
+
+	return version, nil
+}
@@ -0,0 +1,222 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	custom := []byte("package main\n\nimport _ \"github.com/clipperhouse/slice\"\n")
+	main := []byte("package main\n\nfunc main() {}\n")
+
+	deps, err := runnerModules(DefaultConfig, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key1, err := cacheKey(DefaultConfig, custom, main, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key2, err := cacheKey(DefaultConfig, custom, main, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key1 != key2 {
+		t.Errorf("cacheKey should be deterministic, got %s and %s", key1, key2)
+	}
+
+	key3, err := cacheKey(DefaultConfig, custom, []byte("package main\n\nfunc main() { println() }\n"), deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key1 == key3 {
+		t.Error("cacheKey should change with the runner source")
+	}
+
+	more := deps
+	more.mods = append(more.mods, listModule{Path: "example.com/foo", Version: "v1.0.0"})
+
+	key4, err := cacheKey(DefaultConfig, custom, main, more)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key1 == key4 {
+		t.Error("cacheKey should change with the module versions")
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gen_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.cacheDir = filepath.Join(dir, "gen")
+
+	custom := []byte("package main\n\nimport _ \"github.com/clipperhouse/slice\"\n")
+	main := []byte("package main\n\nfunc main() {}\n")
+
+	deps, err := runnerModules(DefaultConfig, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := cacheKey(DefaultConfig, custom, main, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a cached runner is used without building
+	name := "gen"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	writeTree(t, c.cacheDir, map[string]string{
+		filepath.Join(key, name): "not really a runner",
+	})
+
+	exe, err := runner(c, custom, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exe != filepath.Join(c.cacheDir, key, name) {
+		t.Errorf("runner should return the cached runner, got %s", exe)
+	}
+
+	// gen cache prints the location
+	if err := cache(c); err != nil {
+		t.Error(err)
+	}
+
+	if b.String() != c.cacheDir+"\n" {
+		t.Errorf("cache should print %s, got %s", c.cacheDir, b.String())
+	}
+
+	// gen cache clean removes it
+	if err := cache(c, "clean"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := os.Stat(c.cacheDir); err == nil {
+		t.Errorf("cache clean should remove %s", c.cacheDir)
+	}
+}
+
+func TestCacheKeySource(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	custom := []byte("package main\n\nimport _ \"example.com/m/tw\"\n")
+	main := []byte("package main\n\nfunc main() {}\n")
+
+	// a typewriter in the main module, or in GOPATH, has no version; edits to it must change the key
+	for _, mode := range []struct {
+		name, dir string
+		env       map[string]string
+	}{
+		{"module", filepath.Join(root, "m"), map[string]string{"GO111MODULE": "on", "GOFLAGS": "-mod=mod"}},
+		{"GOPATH", filepath.Join(root, "src", "example.com", "m"), map[string]string{"GO111MODULE": "off", "GOPATH": root}},
+	} {
+		for k, v := range mode.env {
+			defer os.Setenv(k, os.Getenv(k))
+			os.Setenv(k, v)
+		}
+
+		writeTree(t, mode.dir, map[string]string{
+			"go.mod":   "module example.com/m\n",
+			"tw/tw.go": "package tw\n",
+		})
+
+		keys := make([]string, 2)
+
+		err := inDir(mode.dir, func() error {
+			for i := range keys {
+				if i > 0 {
+					writeTree(t, mode.dir, map[string]string{"tw/tw.go": "package tw // edited\n"})
+				}
+
+				deps, err := runnerModules(DefaultConfig, custom)
+				if err != nil {
+					return err
+				}
+
+				if keys[i], err = cacheKey(DefaultConfig, custom, main, deps); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("%s: %v", mode.name, err)
+		}
+
+		if keys[0] == keys[1] {
+			t.Errorf("%s: cacheKey should change with the source of a typewriter without a version", mode.name)
+		}
+	}
+}
+
+func TestCacheHit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gen_cache_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tool := newFakeModule()
+
+	c := DefaultConfig
+	c.out = ioutil.Discard
+	c.cacheDir = dir
+	c.tool = tool
+
+	custom := []byte("package main\n\nimport _ \"github.com/clipperhouse/slice\"\n")
+	main := []byte("package main\n\nfunc main() {}\n")
+
+	built, err := runner(c, custom, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tool.called("build") {
+		t.Fatal("runner should build when not cached")
+	}
+
+	tool.calls = nil
+
+	cached, err := runner(c, custom, main)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cached != built {
+		t.Errorf("runner should return the cached %s, got %s", built, cached)
+	}
+
+	// the go version is known from the build, and dependencies are listed once
+	expected := []string{"go env GOMOD", "go list -e -deps -json github.com/clipperhouse/typewriter github.com/clipperhouse/slice"}
+
+	if strings.Join(tool.calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("a cache hit should run only %q, got %q", expected, tool.calls)
+	}
+}
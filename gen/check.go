@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// check generates files for the current package in memory and compares them to those on disk.
+//
+// A unified diff is printed for each out-of-date file, and for each orphaned file (see orphans) as a deletion, and an
+// error is returned if there are any.
+func check(c Config) error {
+	files, types, err := generate(c)
+
+	if err != nil {
+		// the last directive may have been removed, leaving only orphans
+		if hasDirective(c, "./", "+gen") {
+			return err
+		}
+		if names, _ := orphans(c, nil); len(names) == 0 {
+			return err
+		}
+	}
+
+	c.emitTypes(types)
+
+	var stale int
+
+	for _, f := range files {
+		from := "a/" + f.Name
+		existing, err := ioutil.ReadFile(f.Name)
+
+		if os.IsNotExist(err) {
+			from = os.DevNull
+		} else if err != nil {
+			return err
+		}
+
+		if diff := unifiedDiff(from, "b/"+f.Name, existing, f.Src); len(diff) > 0 {
+			if c.json {
+				c.emit(event{Event: "stale", File: f.Name, Diff: diff})
+			} else {
+				fmt.Fprint(c.out, diff)
+			}
+			stale++
+		}
+	}
+
+	names, err := orphans(c, files)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		existing, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		diff := unifiedDiff("a/"+name, os.DevNull, existing, nil)
+
+		if c.json {
+			c.emit(event{Event: "orphan", File: name, Diff: diff})
+		} else {
+			fmt.Fprint(c.out, diff)
+		}
+	}
+
+	switch {
+	case len(names) > 0:
+		return fmt.Errorf("%d generated file(s) out of date, %d no longer produced by any +gen directive; type %s -prune to update", stale, len(names), filepath.Base(os.Args[0]))
+	case stale > 0:
+		return fmt.Errorf("%d generated file(s) out of date; type %s to update", stale, filepath.Base(os.Args[0]))
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_check_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go": "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+
+	err = inDir(root, func() error {
+		// nothing has been generated
+		if err := check(c); err == nil {
+			t.Error("check should fail when generated files are missing")
+		}
+
+		if !strings.Contains(b.String(), "+++ b/a_slice.go") {
+			t.Errorf("check should print a diff for a_slice.go, got:\n%s", b.String())
+		}
+
+		// check must not write anything
+		if _, err := os.Stat("a_slice.go"); err == nil {
+			t.Error("check should not write a_slice.go")
+		}
+
+		if err := run(c); err != nil {
+			return err
+		}
+
+		// up to date
+		b.Reset()
+		if err := check(c); err != nil {
+			t.Errorf("check should succeed after run, got %v:\n%s", err, b.String())
+		}
+
+		if b.Len() > 0 {
+			t.Errorf("check should not output anything when up to date, got:\n%s", b.String())
+		}
+
+		// change the tag, making a_slice.go stale
+		writeTree(t, root, map[string]string{
+			"a.go": "package a\n\n// +gen slice:\"Where, Any\"\ntype A int\n",
+		})
+
+		b.Reset()
+		if err := check(c); err == nil {
+			t.Error("check should fail when a tag has changed")
+		}
+
+		if !strings.Contains(b.String(), "+func (rcv ASlice) Any(") {
+			t.Errorf("check diff should include the new Any method, got:\n%s", b.String())
+		}
+
+		// remove the directive, orphaning a_slice.go
+		writeTree(t, root, map[string]string{
+			"a.go": "package a\n\ntype A int\n",
+		})
+
+		b.Reset()
+		if err := check(c); err == nil || !strings.Contains(err.Error(), "1 no longer produced") {
+			t.Errorf("check should fail when a generated file is orphaned, got %v", err)
+		}
+
+		if !strings.Contains(b.String(), "--- a/a_slice.go\n+++ "+os.DevNull) {
+			t.Errorf("check should print a deletion diff for a_slice.go, got:\n%s", b.String())
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package gen
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// Config is the configuration of gen; see DefaultConfig. It is further set by the project configuration file and flags.
+type Config struct {
+	out        io.Writer
+	in         io.Reader
+	customName string
+	// dryRun sends generated source to out rather than writing files
+	dryRun bool
+	// prune removes orphaned generated files, rather than listing them
+	prune bool
+	// yes, with prune, removes orphaned files without asking; otherwise only a terminal may confirm their removal
+	yes bool
+	// json writes events (see event) to out, in place of the usual output
+	json bool
+	// Files selects the files which gen parses; exported for use in runTmpl
+	Files filter
+	// output is a template for generated file names, executed with outputInfo; the result is lower-cased
+	output *template.Template
+	// typewriters replaces stdImports when there is no custom imports file; nil means standard
+	typewriters typewriter.ImportSpecSet
+	// cacheDir holds compiled custom runners; empty means the user cache directory, see Config.cache
+	cacheDir string
+	// watchInterval is the time watch waits for further changes before running gen
+	watchInterval time.Duration
+	// pollInterval, if set, has watch scan for changes at that interval, rather than using file system events
+	pollInterval time.Duration
+	// tool runs the go tool; see toolchain
+	tool toolchain
+	// inProcess runs typewriters registered in this process, rather than building a runner; see Main
+	inProcess bool
+	// ctx is checked before each package; see Run
+	ctx context.Context
+	// result, if not nil, collects the outcome; see Run
+	result *Result
+	*typewriter.Config
+}
+
+// DefaultConfig is the configuration of the gen command
+var DefaultConfig = Config{
+	out:           os.Stdout,
+	in:            os.Stdin,
+	customName:    "_gen.go",
+	output:        template.Must(template.New("output").Parse("{{.Type}}_{{.TypeWriter}}{{.Test}}.go")),
+	watchInterval: 1 * time.Second,
+	tool:          goToolchain{"go"},
+	ctx:           context.Background(),
+	Config:        &typewriter.Config{},
+}
+
+// withDefaults returns c, its unset fields taken from DefaultConfig, such as all of them for Config{}
+func (c Config) withDefaults() Config {
+	d := DefaultConfig
+
+	if c.out == nil {
+		c.out = d.out
+	}
+	if c.in == nil {
+		c.in = d.in
+	}
+	if len(c.customName) == 0 {
+		c.customName = d.customName
+	}
+	if c.output == nil {
+		c.output = d.output
+	}
+	if c.watchInterval == 0 {
+		c.watchInterval = d.watchInterval
+	}
+	if c.tool == nil {
+		c.tool = d.tool
+	}
+	if c.ctx == nil {
+		c.ctx = d.ctx
+	}
+	if c.Config == nil {
+		c.Config = d.Config
+	}
+
+	return c
+}
+
+// outputInfo is passed to the output template to name generated files
+type outputInfo struct {
+	Type, TypeWriter string
+	// Test is _test if the type is declared in a _test.go file, otherwise empty
+	Test string
+}
+
+// defaultImports returns the typewriters used when there is no custom imports file
+func (c Config) defaultImports() typewriter.ImportSpecSet {
+	if c.typewriters != nil {
+		return c.typewriters
+	}
+	return stdImports
+}
+
+// filter matches file names against glob patterns; see filepath.Match
+type filter struct {
+	// Include, if not empty, limits files to those matching at least one pattern
+	Include []string
+	// Exclude removes files matching any pattern
+	Exclude []string
+}
+
+func (f filter) match(fi os.FileInfo) bool {
+	return matchName(fi.Name(), f.Include, f.Exclude)
+}
+
+// parses reports whether gen parses the named file: a source file (see sourceFile) selected by Files
+func (c Config) parses(fi os.FileInfo) bool {
+	return sourceFile(fi) && c.Files.match(fi)
+}
+
+// excludes reports whether the named file is excluded by Files. Include selects the files gen parses, not those it
+// generates, so only Exclude applies to the latter; see orphans.
+func (c Config) excludes(name string) bool {
+	return !matchName(name, nil, c.Files.Exclude)
+}
+
+// keep in sync with runTmpl
+func matchName(name string, include, exclude []string) bool {
+	matched := len(include) == 0
+
+	for _, pattern := range include {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		return false
+	}
+
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keep in sync with imports.go
+var stdImports = typewriter.NewImportSpecSet(
+	typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/slice"},
+	typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/stringer"},
+)
@@ -0,0 +1,179 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// configNames are the names of project configuration files, searched for in the current directory and its parents
+var configNames = []string{"gen.toml", "gen.json"}
+
+// fileConfig is the project configuration, as read from gen.toml or gen.json. Zero values leave the default in place.
+type fileConfig struct {
+	// CustomName is the name of the custom imports file, by default _gen.go
+	CustomName string `json:"custom_name"`
+	// IgnoreTypeCheckErrors is the equivalent of the -f flag
+	IgnoreTypeCheckErrors bool `json:"ignore_type_check_errors"`
+	// Include and Exclude are glob patterns matched against file names, selecting the files which gen parses
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+	// Output is a template for generated file names, see outputInfo
+	Output string `json:"output"`
+	// TypeWriters are import paths, replacing the standard typewriters when there is no custom imports file
+	TypeWriters []string `json:"typewriters"`
+	Watch       struct {
+		// Interval is the time to wait for further changes before running gen, such as "500ms"
+		Interval string `json:"interval"`
+		// Poll, if set, is the interval at which to scan for changes, rather than using file system events
+		Poll string `json:"poll"`
+	} `json:"watch"`
+}
+
+// findConfigFile returns the path of the nearest project configuration file, or an empty string if there is none
+func findConfigFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		var found []string
+
+		for _, name := range configNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				found = append(found, path)
+			}
+		}
+
+		if len(found) > 1 {
+			return "", fmt.Errorf("found both %s; please use only one", strings.Join(found, " and "))
+		}
+
+		if len(found) == 1 {
+			return found[0], nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readConfigFile reads a gen.toml or gen.json file. Unknown keys are an error.
+func readConfigFile(path string) (fileConfig, error) {
+	var fc fileConfig
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fc, err
+	}
+
+	if filepath.Ext(path) == ".toml" {
+		m, err := parseTOML(src)
+		if err != nil {
+			return fc, fmt.Errorf("%s: %v", path, err)
+		}
+
+		// round trip through json, to use the same field mapping
+		if src, err = json.Marshal(m); err != nil {
+			return fc, err
+		}
+	}
+
+	d := json.NewDecoder(bytes.NewReader(src))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(&fc); err != nil {
+		return fc, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return fc, nil
+}
+
+// apply sets the values of fc on c
+func (fc fileConfig) apply(c *Config) error {
+	if len(fc.CustomName) > 0 {
+		c.customName = fc.CustomName
+	}
+
+	if fc.IgnoreTypeCheckErrors {
+		c.IgnoreTypeCheckErrors = true
+	}
+
+	if len(fc.Include) > 0 || len(fc.Exclude) > 0 {
+		c.Files = filter{fc.Include, fc.Exclude}
+
+		// check patterns up front, rather than on each file
+		for _, pattern := range append(fc.Include, fc.Exclude...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid file pattern %q: %v", pattern, err)
+			}
+		}
+
+		c.Filter = c.Files.match
+	}
+
+	if len(fc.Output) > 0 {
+		tmpl, err := template.New("output").Parse(fc.Output)
+		if err != nil {
+			return fmt.Errorf("invalid output template: %v", err)
+		}
+		c.output = tmpl
+	}
+
+	if len(fc.TypeWriters) > 0 {
+		c.typewriters = typewriter.NewImportSpecSet()
+		for _, path := range fc.TypeWriters {
+			c.typewriters.Add(typewriter.ImportSpec{Name: "_", Path: path})
+		}
+	}
+
+	if len(fc.Watch.Interval) > 0 {
+		d, err := time.ParseDuration(fc.Watch.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid watch interval: %v", err)
+		}
+		c.watchInterval = d
+	}
+
+	if len(fc.Watch.Poll) > 0 {
+		d, err := time.ParseDuration(fc.Watch.Poll)
+		if err != nil {
+			return fmt.Errorf("invalid watch poll interval: %v", err)
+		}
+		c.pollInterval = d
+	}
+
+	return nil
+}
+
+// loadConfigFile finds the nearest project configuration file, if any, and applies it to c
+func loadConfigFile(c *Config) error {
+	path, err := findConfigFile()
+	if err != nil || len(path) == 0 {
+		return err
+	}
+
+	fc, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := fc.apply(c); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	return nil
+}
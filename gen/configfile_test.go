@@ -0,0 +1,299 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestParseTOML(t *testing.T) {
+	src := `
+# a comment
+custom_name = "_gen_custom.go" # trailing comment
+ignore_type_check_errors = true
+exclude = [
+	"*_mock.go",
+	'#literal#', # comment after element
+]
+
+[watch]
+interval = "500ms"
+count = 3
+`
+
+	m, err := parseTOML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"custom_name":              "_gen_custom.go",
+		"ignore_type_check_errors": true,
+		"exclude":                  []interface{}{"*_mock.go", "#literal#"},
+		"watch": map[string]interface{}{
+			"interval": "500ms",
+			"count":    int64(3),
+		},
+	}
+
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("parseTOML should return %v, got %v", expected, m)
+	}
+
+	errs := []string{
+		"foo",
+		"foo = bar",
+		"foo = \"bar",
+		"[watch",
+		"foo = 1\nfoo = 2",
+		"[watch]\n[watch]",
+	}
+
+	for i, src := range errs {
+		if _, err := parseTOML([]byte(src)); err == nil {
+			t.Errorf("errs[%d]: parseTOML should fail on %q", i, src)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_configfile_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"gen.toml": "custom_name = \"_x.go\"\ntypewriters = [\"github.com/clipperhouse/slice\"]\n[watch]\ninterval = \"2s\"\npoll = \"3s\"\n",
+		"gen.json": `{"custom_name": "_x.go", "typewriters": ["github.com/clipperhouse/slice"], "watch": {"interval": "2s", "poll": "3s"}}`,
+		"bad.json": `{"custom_nam": "_x.go"}`,
+	})
+
+	fromTOML, err := readConfigFile(filepath.Join(root, "gen.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJSON, err := readConfigFile(filepath.Join(root, "gen.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromTOML, fromJSON) {
+		t.Errorf("gen.toml and gen.json should be equivalent, got %v and %v", fromTOML, fromJSON)
+	}
+
+	if _, err := readConfigFile(filepath.Join(root, "bad.json")); err == nil {
+		t.Error("unknown keys should be an error")
+	}
+
+	c := DefaultConfig
+	c.Config = &typewriter.Config{}
+
+	if err := fromTOML.apply(&c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.customName != "_x.go" {
+		t.Errorf("customName should be _x.go, got %s", c.customName)
+	}
+
+	if c.watchInterval != 2*time.Second {
+		t.Errorf("watchInterval should be 2s, got %s", c.watchInterval)
+	}
+
+	if c.pollInterval != 3*time.Second {
+		t.Errorf("pollInterval should be 3s, got %s", c.pollInterval)
+	}
+
+	expected := typewriter.NewImportSpecSet(typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/slice"})
+	if !c.defaultImports().Equal(expected) {
+		t.Errorf("defaultImports should be %v, got %v", expected, c.defaultImports())
+	}
+
+	// both files in one directory is ambiguous
+	err = inDir(root, func() error {
+		_, err := findConfigFile()
+		return err
+	})
+
+	if err == nil {
+		t.Error("findConfigFile should fail when both gen.toml and gen.json exist")
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_configfile_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"gen.json":      `{"exclude": ["b.go"], "output": "{{.Type}}_gen_{{.TypeWriter}}{{.Test}}.go"}`,
+		"sub/a.go":      "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+		"sub/b.go":      "package a\n\n// +gen slice:\"Any\"\ntype B int\n",
+		"sub/c_test.go": "package a\n\n// +gen slice:\"Any\"\ntype C int\n",
+	})
+
+	var b bytes.Buffer
+
+	err = inDir(filepath.Join(root, "sub"), func() error {
+		c := DefaultConfig
+		c.out = &b
+		c.Config = &typewriter.Config{}
+
+		// found in the parent directory
+		if err := loadConfigFile(&c); err != nil {
+			return err
+		}
+
+		return run(c)
+	})
+
+	if err != nil {
+		t.Fatalf("%s\n%s", err, b.String())
+	}
+
+	for _, f := range []string{"a_gen_slice.go", "c_gen_slice_test.go"} {
+		if _, err := os.Stat(filepath.Join(root, "sub", f)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// b.go is excluded
+	if _, err := os.Stat(filepath.Join(root, "sub", "b_gen_slice.go")); err == nil {
+		t.Error("b_gen_slice.go should not be generated from an excluded file")
+	}
+
+	if b.Len() > 0 {
+		t.Errorf("run should not output anything, got:\n%s", b.String())
+	}
+}
+
+func TestConfigFileFilters(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_configfile_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"gen.json":    `{"exclude": ["b*.go"]}`,
+		"a/a.go":      "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+		"b/b.go":      "package b\n\n// +gen slice:\"Where\"\ntype B int\n",
+		"c/c.go":      "package c\n\ntype C int\n",
+		"c/b_x.go":    "// Generated by: gen\n// TypeWriter: slice\n// Directive: +gen on X\n\npackage c\n",
+		"c/c_x.go":    "// Generated by: gen\n// TypeWriter: slice\n// Directive: +gen on X\n\npackage c\n",
+		"d/d.go":      "package d\n\ntype D int\n",
+		"d/b_x.go":    "// Generated by: gen\n// TypeWriter: slice\n// Directive: +gen on X\n\npackage d\n",
+		"e/e.go":      "package e\n\ntype E int\n",
+		"e/b_test.go": "package e\n\n// +gen slice:\"Where\"\ntype T int\n",
+	})
+
+	err = inDir(root, func() error {
+		c := DefaultConfig
+		c.Config = &typewriter.Config{}
+
+		if err := loadConfigFile(&c); err != nil {
+			return err
+		}
+
+		// b holds directives only in excluded files, d only excluded generated files
+		dirs, err := packages(c, []string{"./..."})
+		if err != nil {
+			return err
+		}
+
+		if strings.Join(dirs, " ") != "a c" {
+			t.Errorf("packages should return a and c, got %v", dirs)
+		}
+
+		return inDir("c", func() error {
+			names, err := orphans(c, nil)
+			if err != nil {
+				return err
+			}
+
+			if strings.Join(names, " ") != "c_x.go" {
+				t.Errorf("orphans should ignore the excluded b_x.go, got %v", names)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = inDir(filepath.Join(root, "e"), func() error {
+		c := DefaultConfig
+		c.Config = &typewriter.Config{}
+
+		if err := loadConfigFile(&c); err != nil {
+			return err
+		}
+
+		tests, err := testTypes(c)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := tests["T"]; ok {
+			t.Error("testTypes should ignore the excluded b_test.go")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigFileBroken(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_configfile_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"gen.toml": "custom_name = \n",
+		"a.go":     "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+	})
+
+	err = inDir(root, func() error {
+		for _, args := range [][]string{{"gen", "help"}, {"gen", "help", "check"}, {"gen", "-h"}, {"gen", "check", "-h"}} {
+			var b bytes.Buffer
+			c := DefaultConfig
+			c.out = &b
+
+			// help is for fixing the configuration file
+			if err := runMain(c, args); err != nil {
+				t.Errorf("%v should succeed despite a broken gen.toml, got %v", args, err)
+			}
+		}
+
+		c := DefaultConfig
+		c.out = ioutil.Discard
+
+		if err := runMain(c, []string{"gen", "check"}); err == nil || !strings.Contains(err.Error(), "gen.toml") {
+			t.Errorf("check should report the broken gen.toml, got %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,177 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// the number of unchanged lines surrounding each hunk of a diff
+const diffContext = 3
+
+type edit struct {
+	op   byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns a unified diff of from and to, or an empty string if they are equal
+func unifiedDiff(fromName, toName string, from, to []byte) string {
+	if bytes.Equal(from, to) {
+		return ""
+	}
+
+	edits := diffLines(splitLines(from), splitLines(to))
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	for i := 0; i < len(edits); {
+		// find the next change
+		start := i
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+
+		if start == len(edits) {
+			break
+		}
+
+		// extend the hunk while changes are close enough to share context
+		end := start
+		for j := start; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+				continue
+			}
+			if j-end >= 2*diffContext {
+				break
+			}
+		}
+
+		first := start - diffContext
+		if first < 0 {
+			first = 0
+		}
+
+		last := end + diffContext
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		// line numbers are 1-based; a zero-length range refers to the preceding line
+		fromLine, toLine := lineCounts(edits[:first])
+		fromLen, toLen := lineCounts(edits[first:last])
+		if fromLen > 0 {
+			fromLine++
+		}
+		if toLen > 0 {
+			toLine++
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", fromLine, fromLen, toLine, toLen)
+
+		for _, e := range edits[first:last] {
+			b.WriteByte(e.op)
+			b.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = last
+	}
+
+	return b.String()
+}
+
+// lineCounts returns the number of lines of the 'from' and 'to' sides represented by edits
+func lineCounts(edits []edit) (from, to int) {
+	for _, e := range edits {
+		if e.op != '+' {
+			from++
+		}
+		if e.op != '-' {
+			to++
+		}
+	}
+	return from, to
+}
+
+func splitLines(b []byte) []string {
+	lines := strings.SplitAfter(string(b), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a minimal edit script from a to b, by way of a longest common subsequence
+func diffLines(a, b []string) []edit {
+	var edits []edit
+
+	// common prefix and suffix are unchanged, no need to include them in the (quadratic) lcs
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, l := range a[:prefix] {
+		edits = append(edits, edit{' ', l})
+	}
+
+	x, y := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int32, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(y)+1)
+	}
+
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			edits = append(edits, edit{' ', x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', x[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', y[j]})
+			j++
+		}
+	}
+
+	for ; i < len(x); i++ {
+		edits = append(edits, edit{'-', x[i]})
+	}
+
+	for ; j < len(y); j++ {
+		edits = append(edits, edit{'+', y[j]})
+	}
+
+	for _, l := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', l})
+	}
+
+	return edits
+}
@@ -0,0 +1,25 @@
+package gen
+
+import "testing"
+
+type diffTest struct {
+	from, to string
+	diff     string
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []diffTest{
+		diffTest{"a\nb\nc\n", "a\nb\nc\n", ""},
+		diffTest{"", "a\n", "--- from\n+++ to\n@@ -0,0 +1,1 @@\n+a\n"},
+		diffTest{"a\nb\nc\n", "a\nx\nc\n", "--- from\n+++ to\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		diffTest{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n", "1\n2\nx\n4\n5\n6\n7\n8\n9\n10\ny\n12\n",
+			"--- from\n+++ to\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+x\n 4\n 5\n 6\n@@ -8,5 +8,5 @@\n 8\n 9\n 10\n-11\n+y\n 12\n"},
+		diffTest{"a\nb", "a\nc", "--- from\n+++ to\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+	}
+
+	for i, test := range tests {
+		if diff := unifiedDiff("from", "to", []byte(test.from), []byte(test.to)); diff != test.diff {
+			t.Errorf("tests[%d]: diff should be\n%s\ngot\n%s", i, test.diff, diff)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// event is a line of JSON output, written by commands given the -json flag in place of their usual output.
+// Event is one of:
+//
+//	package     a package is about to be scanned, in Dir
+//	type        a type marked with +gen was found, see Type
+//	write       a generated File was written
+//	generate    a generated File was not written (dry run), see Source
+//	stale       a generated File is out of date (check), see Diff
+//	orphan      a generated File is no longer produced by any +gen directive; see Diff, from check
+//	remove      an orphaned File was removed
+//	typewriter  a typewriter is available (list), see TypeWriter
+//	output      a Message was printed, such as by a typewriter
+//	error       an error Message, with its File, Line and Column if known
+//	diagnostic  a problem Message found by gen vet or gen list types, with its File, Line and Column
+//	warning     a warning Message, such as an invalid +gen tag ignored by -f, with its File, Line and Column
+//	run         gen was run by watch, as files Changed, having Written files, taking Elapsed seconds; errors precede it
+type event struct {
+	Event      string          `json:"event"`
+	Dir        string          `json:"dir,omitempty"`
+	Type       *typeInfo       `json:"type,omitempty"`
+	TypeWriter *typewriterInfo `json:"typewriter,omitempty"`
+	File       string          `json:"file,omitempty"`
+	Line       int             `json:"line,omitempty"`
+	Column     int             `json:"column,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Diff       string          `json:"diff,omitempty"`
+	Changed    []string        `json:"changed,omitempty"`
+	Written    []string        `json:"written,omitempty"`
+	Elapsed    float64         `json:"elapsed,omitempty"`
+}
+
+// emitTypes emits type events, if -json
+func (c Config) emitTypes(types []typeInfo) {
+	if !c.json {
+		return
+	}
+
+	for i := range types {
+		c.emit(event{Event: "type", Type: &types[i]})
+	}
+}
+
+// emit writes e to c.out, as a line of JSON
+func (c Config) emit(e event) {
+	json.NewEncoder(c.out).Encode(e)
+}
+
+// emitLines emits an event of kind for each non-empty line of s; see errorEvent
+func (c Config) emitLines(kind, dir, s string) {
+	for _, line := range strings.Split(s, "\n") {
+		if len(strings.TrimSpace(line)) == 0 || exitStatusMsg.MatchString(line) {
+			continue
+		}
+
+		e := errorEvent(line)
+		e.Event = kind
+		e.Dir = dir
+		c.emit(e)
+	}
+}
+
+var position = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)? (.*)$`)
+
+// errorEvent returns an error event for a line of an error message, taking its position, if any, from a file:line:col prefix
+func errorEvent(line string) event {
+	e := event{
+		Event:   "error",
+		Message: line,
+	}
+
+	// type check errors are prefixed
+	line = strings.TrimPrefix(line, "[ignored] ")
+
+	if m := position.FindStringSubmatch(line); m != nil {
+		e.File = m[1]
+		e.Line, _ = strconv.Atoi(m[2])
+		e.Column, _ = strconv.Atoi(m[3])
+		e.Message = m[4]
+	}
+
+	return e
+}
+
+// typeInfo describes a type marked with +gen, and the tags of its directive.
+// It is passed as JSON from the run pipeline back to gen; keep in sync with runTmpl.
+type typeInfo struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Pointer bool   `json:"pointer,omitempty"`
+	// Comparable, Numeric and Ordered are the constraints the type satisfies; see typewriter.Constraint
+	Comparable bool      `json:"comparable"`
+	Numeric    bool      `json:"numeric"`
+	Ordered    bool      `json:"ordered"`
+	Tags       []tagInfo `json:"tags,omitempty"`
+	// Files are the files which would be written for the type, keyed by typewriter; only for list types
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// directive returns the +gen directive of t, as it might be written
+func (t typeInfo) directive() string {
+	var b strings.Builder
+
+	b.WriteString("+gen")
+
+	if t.Pointer {
+		b.WriteString(" *")
+	}
+
+	for _, tag := range t.Tags {
+		b.WriteString(" " + tag.Name)
+
+		if len(tag.Values) == 0 && !tag.Negated {
+			continue
+		}
+
+		var values []string
+		for _, v := range tag.Values {
+			if len(v.TypeParameters) > 0 {
+				values = append(values, v.Name+"["+strings.Join(v.TypeParameters, ", ")+"]")
+				continue
+			}
+			values = append(values, v.Name)
+		}
+
+		negated := ""
+		if tag.Negated {
+			negated = "-"
+		}
+
+		fmt.Fprintf(&b, ":%q", negated+strings.Join(values, ","))
+	}
+
+	return b.String()
+}
+
+type tagInfo struct {
+	Name    string         `json:"name"`
+	Negated bool           `json:"negated,omitempty"`
+	Values  []tagValueInfo `json:"values,omitempty"`
+}
+
+type tagValueInfo struct {
+	Name           string   `json:"name"`
+	TypeParameters []string `json:"typeParameters,omitempty"`
+}
+
+// typewriterInfo describes an available typewriter.
+// It is passed as JSON from the list pipeline back to gen; keep in sync with listTmpl.
+type typewriterInfo struct {
+	Name string `json:"name"`
+	// Path is the import path of the typewriter's package
+	Path string `json:"path"`
+	// Version is that of the module providing Path, as built; empty outside of module mode
+	Version string `json:"version,omitempty"`
+	// Source is where the typewriter was imported from; see typewriterSources
+	Source string `json:"source,omitempty"`
+}
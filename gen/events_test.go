@@ -0,0 +1,126 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestErrorEvent(t *testing.T) {
+	tests := []struct {
+		text         string
+		file         string
+		line, column int
+		message      string
+	}{
+		{"a.go:4:8: undefined: Missing", "a.go", 4, 8, "undefined: Missing"},
+		{"[ignored] a.go:4:8: undefined: Missing", "a.go", 4, 8, "undefined: Missing"},
+		{"a.go:12: expected ';'", "a.go", 12, 0, "expected ';'"},
+		{"No types marked with +gen were found.", "", 0, 0, "No types marked with +gen were found."},
+	}
+
+	for _, test := range tests {
+		e := errorEvent(test.text)
+
+		if e.Event != "error" || e.File != test.file || e.Line != test.line || e.Column != test.column || e.Message != test.message {
+			t.Errorf("errorEvent(%q) should be %s:%d:%d %q, got %+v", test.text, test.file, test.line, test.column, test.message, e)
+		}
+	}
+}
+
+// events decodes JSON lines
+func events(t *testing.T, b []byte) []event {
+	var result []event
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	for d.More() {
+		var e event
+		if err := d.Decode(&e); err != nil {
+			t.Fatalf("output should be JSON events, got %v:\n%s", err, b)
+		}
+		result = append(result, e)
+	}
+
+	return result
+}
+
+func TestJSON(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_events_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go":       "package a\n\n// +gen * slice:\"Where,GroupBy[string]\"\ntype A int\n",
+		"b_slice.go": "// Generated by: gen\n// TypeWriter: slice\n// Directive: +gen on B\n\npackage a\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.json = true
+
+	err = inDir(root, func() error {
+		// check first, nothing has been written
+		if err := forPatterns(c, nil, check); err == nil {
+			t.Error("check should fail when generated files are missing")
+		}
+
+		// b_slice.go is orphaned
+		expected := []string{"package", "type", "stale", "orphan", "error"}
+		got := events(t, b.Bytes())
+
+		if len(got) != len(expected) {
+			t.Fatalf("check should emit %v, got %+v", expected, got)
+		}
+
+		for i, e := range got {
+			if e.Event != expected[i] {
+				t.Errorf("event %d should be %s, got %+v", i, expected[i], e)
+			}
+		}
+
+		typ := got[1].Type
+		if typ == nil || typ.Name != "A" || !typ.Pointer || len(typ.Tags) != 1 || len(typ.Tags[0].Values) != 2 {
+			t.Errorf("type event should describe *A with a slice tag of 2 values, got %+v", typ)
+		} else if tps := typ.Tags[0].Values[1].TypeParameters; len(tps) != 1 || tps[0] != "string" {
+			t.Errorf("type event should include the type parameter string, got %v", tps)
+		}
+
+		if got[2].File != "a_slice.go" || len(got[2].Diff) == 0 {
+			t.Errorf("stale event should include the diff of a_slice.go, got %+v", got[2])
+		}
+
+		b.Reset()
+
+		if err := forPatterns(c, nil, run); err != nil {
+			return err
+		}
+
+		expected = []string{"package", "type", "write", "orphan"}
+		got = events(t, b.Bytes())
+
+		if len(got) != len(expected) {
+			t.Fatalf("run should emit %v, got %+v", expected, got)
+		}
+
+		for i, e := range got {
+			if e.Event != expected[i] {
+				t.Errorf("event %d should be %s, got %+v", i, expected[i], e)
+			}
+		}
+
+		if got[2].File != "a_slice.go" || got[3].File != "b_slice.go" {
+			t.Errorf("run should write a_slice.go and list b_slice.go as an orphan, got %+v", got)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,120 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/template"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// execute runs a gen command by first determining whether a custom imports file (typically _gen.go) exists
+//
+// If no custom file exists, it executes the passed 'standard' func, unless the project configuration specifies typewriters
+// other than the standard ones, in which case those are used as if they were a custom file.
+//
+// If the custom file exists, a runner program is compiled (or taken from the cache) and executed in the shell.
+//
+// If typewriters were registered in-process (see Main), the standard func is always executed.
+func execute(standard func(c Config) error, c Config, imports typewriter.ImportSpecSet, body *template.Template) error {
+	// typewriters are linked in, see Main
+	if c.inProcess {
+		return standard(c)
+	}
+
+	if importsSrc, err := os.Open(c.customName); err == nil {
+		defer importsSrc.Close()
+
+		// custom imports file exists, use it
+		return executeCustom(importsSrc, c, imports, body)
+	}
+
+	// typewriters configured in the project file, in place of the standard ones
+	if !c.defaultImports().Equal(stdImports) {
+		var importsSrc bytes.Buffer
+
+		p := pkg{
+			Name:    "main",
+			Imports: c.defaultImports(),
+		}
+
+		if err := tmpl.Execute(&importsSrc, p); err != nil {
+			return err
+		}
+
+		return executeCustom(&importsSrc, c, imports, body)
+	}
+
+	// do it the regular way
+	return standard(c)
+}
+
+// executeJSON is execute, for a standard func and body which write a JSON result as the last line of output.
+// Any preceding output, such as from typewriters, is passed through (see passThrough); the result is decoded into v.
+func executeJSON(standard func(c Config) error, c Config, imports typewriter.ImportSpecSet, body *template.Template, v interface{}) error {
+	// capture output
+	var b bytes.Buffer
+	out := c.out
+	c.out = &b
+
+	err := execute(standard, c, imports, body)
+	c.out = out
+
+	if err != nil {
+		c.passThrough(b.Bytes())
+		return err
+	}
+
+	output := bytes.TrimRight(b.Bytes(), "\n")
+	i := bytes.LastIndexByte(output, '\n') + 1
+
+	c.passThrough(output[:i])
+
+	return json.Unmarshal(output[i:], v)
+}
+
+// executeCustom generates a main() using the passed imports and body, to be compiled along with importsSrc.
+//
+// The compiled runner is cached (see runner), and then executed via os.Command.
+func executeCustom(importsSrc io.Reader, c Config, imports typewriter.ImportSpecSet, body *template.Template) error {
+	// the custom typewriters (from _gen.go)
+	custom, err := ioutil.ReadAll(importsSrc)
+	if err != nil {
+		return err
+	}
+
+	var main bytes.Buffer
+
+	p := pkg{
+		Name:    "main",
+		Imports: imports,
+	}
+
+	// execute the package declaration and imports
+	if err := tmpl.Execute(&main, p); err != nil {
+		return err
+	}
+
+	// write the body, usually a main()
+	if err := body.Execute(&main, c); err != nil {
+		return err
+	}
+
+	exe, err := runner(c, custom, main.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// call the runner & send back output/err
+	return c.tool.Exec(exe, c.out, c.out)
+}
+
+var tmpl = template.Must(template.New("package").Parse(`package {{.Name}}
+{{if gt (len .Imports) 0}}
+import ({{range .SortedImports}}
+	{{.Name}} "{{.Path}}"{{with index $.Versions .Path}} // {{.}}{{end}}{{end}}
+)
+{{end}}`))
@@ -0,0 +1,198 @@
+package gen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestExecuteInProcess(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_execute_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"_gen.go": "package main\n\nimport _ \"github.com/clipperhouse/foowriter\"\n",
+	})
+
+	var b bytes.Buffer
+	tool := newFakeModule()
+
+	c := DefaultConfig
+	c.out = &b
+	c.tool = tool
+	c.inProcess = true
+
+	body := template.Must(template.New("body").Parse("func main() {}\n"))
+
+	var standard bool
+
+	err = inDir(root, func() error {
+		return execute(func(c Config) error {
+			standard = true
+			return nil
+		}, c, stdImports, body)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !standard {
+		t.Error("execute should run in-process, ignoring _gen.go")
+	}
+
+	if len(tool.calls) > 0 {
+		t.Errorf("execute should not run the go tool in-process, got %v", tool.calls)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds runners with the go tool")
+	}
+
+	// the declarations of gen, which the templates copy
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	funcs := make(map[string]string)
+	fields := make(map[string]map[string]string)
+	for _, f := range pkgs["gen"].Files {
+		declSources(t, fset, f, funcs, fields)
+	}
+
+	custom := templateSource(t, tmpl, pkg{Name: "main", Imports: stdImports})
+
+	deps, err := runnerModules(DefaultConfig, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	temp, err := ioutil.TempDir("", "gen_templates_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(temp)
+
+	bodies := []struct {
+		imports typewriter.ImportSpecSet
+		body    *template.Template
+	}{
+		{runImports, runTmpl},
+		{listImports, listTmpl},
+	}
+
+	for _, b := range bodies {
+		name := b.body.Name()
+		main := append(templateSource(t, tmpl, pkg{Name: "main", Imports: b.imports}), templateSource(t, b.body, DefaultConfig)...)
+
+		var out bytes.Buffer
+		c := DefaultConfig
+		c.out = &out
+
+		if err := build(c, custom, main, deps, filepath.Join(temp, name)); err != nil {
+			t.Errorf("%s: %v\n%s", name, err, out.String())
+			continue
+		}
+
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, name+".go", main, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		runFuncs := make(map[string]string)
+		runFields := make(map[string]map[string]string)
+		declSources(t, fset, f, runFuncs, runFields)
+
+		for decl, src := range runFuncs {
+			// the runner's own run and main are not copies
+			if decl == "run" || decl == "main" {
+				continue
+			}
+			if want, ok := funcs[decl]; ok && src != want {
+				t.Errorf("%s: %s differs from gen's; keep them in sync\ngot:\n%s\nwant:\n%s", name, decl, src, want)
+			}
+		}
+
+		// structs are passed as JSON, so the runner's fields need only be among gen's
+		for decl, fs := range runFields {
+			want, ok := fields[decl]
+			if !ok {
+				continue
+			}
+			for field, typ := range fs {
+				if want[field] != typ {
+					t.Errorf("%s: field %s.%s of type %s is not in gen's %s", name, decl, field, typ, decl)
+				}
+			}
+		}
+	}
+}
+
+// templateSource executes t with data
+func templateSource(t *testing.T, tmpl *template.Template, data interface{}) []byte {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// declSources adds the source of the top-level functions and non-struct types of f to funcs, and the fields of its structs to fields,
+// keyed by lower-case name, as JSON matches them. Comments are not included.
+func declSources(t *testing.T, fset *token.FileSet, f *ast.File, funcs map[string]string, fields map[string]map[string]string) {
+	source := func(node interface{}) string {
+		var b bytes.Buffer
+		if err := printer.Fprint(&b, fset, node); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				funcs[d.Name.Name] = source(d)
+			}
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					funcs[ts.Name.Name] = source(ts)
+					continue
+				}
+				fs := make(map[string]string)
+				for _, field := range st.Fields.List {
+					for _, n := range field.Names {
+						fs[strings.ToLower(n.Name)] = source(field.Type)
+					}
+				}
+				fields[ts.Name.Name] = fs
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clipperhouse/typewriter"
+	"golang.org/x/tools/imports"
+)
+
+// generated is the unformatted output of a single typewriter for a single type.
+// It is passed as JSON from the run pipeline (in-process or custom) back to gen; keep in sync with runTmpl.
+type generated struct {
+	Type, TypeWriter string
+	Src              []byte
+}
+
+// runOutput is the result of the run pipeline, passed as JSON back to gen; keep in sync with runTmpl
+type runOutput struct {
+	Types     []typeInfo
+	Problems  []problem
+	Generated []generated
+}
+
+// typesOf describes the types marked with +gen in app; keep in sync with runTmpl
+func typesOf(app *typewriter.App) []typeInfo {
+	var types []typeInfo
+
+	for _, p := range app.Packages {
+		for _, t := range p.Types {
+			info := typeInfo{
+				Package:    p.Name(),
+				Name:       t.Name,
+				Pointer:    bool(t.Pointer),
+				Comparable: typewriter.Constraint{Comparable: true}.TryType(t) == nil,
+				Numeric:    typewriter.Constraint{Numeric: true}.TryType(t) == nil,
+				Ordered:    typewriter.Constraint{Ordered: true}.TryType(t) == nil,
+			}
+
+			for _, tag := range t.Tags {
+				ti := tagInfo{
+					Name:    tag.Name,
+					Negated: tag.Negated,
+				}
+
+				for _, v := range tag.Values {
+					vi := tagValueInfo{Name: v.Name}
+					for _, tp := range v.TypeParameters {
+						vi.TypeParameters = append(vi.TypeParameters, tp.String())
+					}
+					ti.Values = append(ti.Values, vi)
+				}
+
+				info.Tags = append(info.Tags, ti)
+			}
+
+			types = append(types, info)
+		}
+	}
+
+	return types
+}
+
+// file is a formatted, generated file, ready to be written
+type file struct {
+	Name string
+	Src  []byte
+	// Type and TypeWriter produced the file
+	Type, TypeWriter string
+}
+
+// generateAll writes the code for all Types and TypeWriters in app into memory.
+//
+// It mirrors typewriter.App.WriteAll, leaving naming, validation and formatting to format.
+func generateAll(app *typewriter.App) ([]generated, error) {
+	var gens []generated
+
+	for _, p := range app.Packages {
+		for _, t := range p.Types {
+			for _, tw := range app.TypeWriters {
+				var b bytes.Buffer
+
+				// start with byline at top, give future readers some background; it names gen whatever the binary,
+				// such as a host of Run or Analyzer, so that generated files don't differ by who wrote them
+				fmt.Fprintf(&b, "// Generated by: gen\n// TypeWriter: %s\n// Directive: %s on %s\n\n", tw.Name(), app.Directive, t.String())
+				fmt.Fprintf(&b, "package %s\n\n", p.Name())
+
+				if imps := tw.Imports(t); len(imps) > 0 {
+					b.WriteString("import (\n")
+					for _, imp := range imps {
+						fmt.Fprintf(&b, "\t%s %q\n", imp.Name, imp.Path)
+					}
+					b.WriteString(")\n")
+				}
+
+				n := b.Len()
+
+				if err := tw.Write(&b, t); err != nil {
+					return gens, err
+				}
+
+				// don't generate a file if no bytes were written by the typewriter
+				if b.Len() == n {
+					continue
+				}
+
+				gens = append(gens, generated{t.Name, tw.Name(), b.Bytes()})
+			}
+		}
+	}
+
+	return gens, nil
+}
+
+// format names, validates and formats generated code, returning files sorted by name
+func format(c Config, gens []generated) ([]file, error) {
+	tests, err := testTypes(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []file
+
+	for _, g := range gens {
+		name, err := fileName(c, g, tests)
+		if err != nil {
+			return nil, err
+		}
+
+		// validate generated ast's before committing to files
+		if _, err := parser.ParseFile(token.NewFileSet(), name, g.Src, 0); err != nil {
+			return nil, err
+		}
+
+		// format, remove unused imports
+		src, err := imports.Process(name, g.Src, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file{name, src, g.Type, g.TypeWriter})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	return files, nil
+}
+
+// fileName names the file for generated code using the output template. By default, it is
+// type_typewriter.go, or type_typewriter_test.go if the source type is in a _test.go file.
+func fileName(c Config, g generated, tests map[string]struct{}) (string, error) {
+	info := outputInfo{
+		Type:       g.Type,
+		TypeWriter: g.TypeWriter,
+	}
+
+	if _, ok := tests[g.Type]; ok {
+		info.Test = "_test"
+	}
+
+	var b bytes.Buffer
+	if err := c.output.Execute(&b, info); err != nil {
+		return "", err
+	}
+
+	name := strings.ToLower(b.String())
+
+	if filepath.Base(name) != name || filepath.Ext(name) != ".go" {
+		return "", fmt.Errorf("output file name %q must be a .go file in the current directory", name)
+	}
+
+	// a type declared in a test file can only be referenced from a test file
+	if len(info.Test) > 0 && !strings.HasSuffix(name, "_test.go") {
+		return "", fmt.Errorf("output file name %q for %s must end in _test.go; use {{.Test}} in the output template", name, g.Type)
+	}
+
+	return name, nil
+}
+
+// testTypes returns the names of types declared in _test.go files in the current directory, which gen parses
+func testTypes(c Config) (map[string]struct{}, error) {
+	filter := func(fi os.FileInfo) bool {
+		return strings.HasSuffix(fi.Name(), "_test.go") && c.parses(fi)
+	}
+
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "./", filter, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]struct{})
+
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, d := range f.Decls {
+				g, ok := d.(*ast.GenDecl)
+				if !ok || g.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range g.Specs {
+					result[spec.(*ast.TypeSpec).Name.Name] = s
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package gen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// get runs `go get` for required typewriters, either default or specified in _gen.go
+//
+// In module mode, typewriters are fetched at their pinned versions (see parseImports) into a scratch module,
+// leaving the user's go.mod alone.
+func get(c Config, args ...string) error {
+	imports, err := getTypewriterImports(c)
+
+	if err != nil {
+		return err
+	}
+
+	versions, err := getTypewriterVersions(c)
+
+	if err != nil {
+		return err
+	}
+
+	modules, err := moduleMode(c)
+
+	if err != nil {
+		return err
+	}
+
+	// we just want the paths, with versions if in module mode
+	var imps []string
+	for imp := range imports {
+		path := imp.Path
+		if v, ok := versions[path]; ok && modules {
+			path += "@" + v
+		}
+		imps = append(imps, path)
+	}
+
+	get := []string{"get"}
+	get = append(get, args...)
+	get = append(get, imps...)
+
+	var dir string
+
+	if modules {
+		scratch, err := getScratchModule()
+		if err != nil {
+			return err
+		}
+		defer removeTempDir(scratch)
+
+		dir = scratch
+	}
+
+	return c.tool.Go(dir, nil, c.out, c.out, get...)
+}
+
+func getTypewriterImports(c Config) (typewriter.ImportSpecSet, error) {
+	imports := typewriter.NewImportSpecSet()
+
+	// check for existence of custom file
+	if src, err := os.Open(c.customName); err == nil {
+		defer src.Close()
+
+		// custom file exists, parse its imports
+		imports, _, err = parseImports(src)
+		if err != nil {
+			return imports, err
+		}
+	} else {
+		// doesn't exist, use standard or configured (clone it)
+		imports = c.defaultImports().Clone()
+	}
+
+	return imports, nil
+}
+
+// getTypewriterVersions returns the pinned versions of typewriters in the custom file, keyed by import path
+func getTypewriterVersions(c Config) (map[string]string, error) {
+	if src, err := os.Open(c.customName); err == nil {
+		defer src.Close()
+
+		_, versions, err := parseImports(src)
+		return versions, err
+	}
+
+	return make(map[string]string), nil
+}
+
+// parseImports parses the imports of a custom file (src is as for parser.ParseFile).
+//
+// Versions are pinned by a line comment following the import, such as:
+//
+//	_ "github.com/clipperhouse/foowriter" // v1.2.0
+//
+// The version must come first in the comment; any remaining text is left alone.
+func parseImports(src interface{}) (typewriter.ImportSpecSet, map[string]string, error) {
+	imports := typewriter.NewImportSpecSet()
+	versions := make(map[string]string)
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return imports, versions, err
+	}
+
+	// convert ast imports into ImportSpecs
+	for _, v := range f.Imports {
+		imp := typewriter.ImportSpec{
+			Path: strings.Trim(v.Path.Value, `"`), // lose the quotes
+		}
+
+		if v.Name != nil {
+			imp.Name = v.Name.Name
+		}
+
+		imports.Add(imp)
+
+		if version, ok := pinnedVersion(v.Comment); ok {
+			versions[imp.Path] = version
+		}
+	}
+
+	return imports, versions, nil
+}
+
+var versionPrefix = regexp.MustCompile(`^v[0-9]`)
+
+// pinnedVersion returns the version at the start of an import's line comment, if any
+func pinnedVersion(comment *ast.CommentGroup) (string, bool) {
+	if comment == nil {
+		return "", false
+	}
+
+	fields := strings.Fields(comment.Text())
+	if len(fields) == 0 || !versionPrefix.MatchString(fields[0]) {
+		return "", false
+	}
+
+	return fields[0], true
+}
@@ -0,0 +1,128 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestGet(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_get_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var b bytes.Buffer
+	tool := newFakeModule()
+
+	c := DefaultConfig
+	c.out = &b
+	c.tool = tool
+	c.customName = filepath.Join(root, "_gen.go")
+
+	// standard
+	imps, err := getTypewriterImports(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(imps) != 2 {
+		t.Errorf("should return 2 imports, got %v", len(imps))
+	}
+
+	if err := add(c, "github.com/clipperhouse/foowriter", "github.com/clipperhouse/setwriter"); err != nil {
+		t.Error(err)
+	}
+
+	// custom file now exists
+	imps2, err := getTypewriterImports(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(imps2) != 4 {
+		t.Errorf("should return 4 custom imports, got %v", len(imps2))
+	}
+
+	// custom get
+	if err := get(c); err != nil {
+		t.Error(err)
+	}
+
+	// custom get with param
+	if err := get(c, "-u"); err != nil {
+		t.Error(err)
+	}
+
+	// pinned versions are got
+	for _, call := range []string{"github.com/clipperhouse/foowriter@v1.2.3", "go get -u "} {
+		if !tool.called(call) {
+			t.Errorf("get should run %q, got %v", call, tool.calls)
+		}
+	}
+
+	// failures are reported
+	tool.fail = map[string]string{"get": "no such module"}
+
+	if err := get(c); err == nil {
+		t.Error("get should fail when go get fails")
+	}
+}
+
+func TestVersions(t *testing.T) {
+	// use custom name so test won't interfere with a real _gen.go
+	c := DefaultConfig
+	c.customName = "_gen_versions_test.go"
+
+	// clean up when done
+	defer os.Remove(c.customName)
+
+	// no custom file, no pins
+	versions, err := getTypewriterVersions(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(versions) != 0 {
+		t.Errorf("should return no versions, got %v", versions)
+	}
+
+	imps := c.defaultImports().Clone()
+	imps.Add(typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/foowriter"})
+
+	pins := map[string]string{
+		"github.com/clipperhouse/foowriter": "v1.2.0",
+	}
+
+	if err := createCustomFile(c, imps, pins); err != nil {
+		t.Fatal(err)
+	}
+
+	imps2, err := getTypewriterImports(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !imps2.Equal(imps) {
+		t.Errorf("imports should round trip, got %v", imps2)
+	}
+
+	versions2, err := getTypewriterVersions(c)
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(versions2) != 1 || versions2["github.com/clipperhouse/foowriter"] != "v1.2.0" {
+		t.Errorf("versions should round trip, got %v", versions2)
+	}
+}
@@ -0,0 +1,127 @@
+package gen
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// help prints usage for gen, or for the command named in args
+func help(c Config, args ...string) error {
+	if len(args) > 0 {
+		cmd, ok := lookup(args[0])
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		return usage(c, cmd)
+	}
+
+	cmd := filepath.Base(os.Args[0])
+	spacer := strings.Repeat(" ", len(cmd))
+
+	info := helpInfo{
+		Name:       cmd,
+		CustomName: c.customName,
+		Spacer:     spacer,
+	}
+
+	if err := helpTmpl.Execute(c.out, info); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type helpInfo struct {
+	Name, CustomName, Spacer string
+}
+
+var helpTmpl = template.Must(template.New("help").Parse(`
+Usage:
+  {{.Name}}           Generate files for types marked with +{{.Name}}.
+  {{.Spacer}}           Optional package patterns, such as ./... for all
+  {{.Spacer}}           packages beneath the current directory.
+  {{.Spacer}}           Unknown typewriters and invalid tag values in +{{.Name}}
+  {{.Spacer}}           directives are reported as errors.
+  {{.Spacer}}           Optional flags: [-f] ignore type check errors and
+  {{.Spacer}}           invalid tags, warning of them,
+  {{.Spacer}}           [-n | --dry-run] print generated files, don't write,
+  {{.Spacer}}           [--prune] remove orphaned generated files, asking,
+  {{.Spacer}}           [-y] with --prune, remove them without asking,
+  {{.Spacer}}           [--json] write JSON events, also for check, list & watch.
+  {{.Name}} check     Verify that generated files are up to date, printing a
+  {{.Spacer}}           diff of any that are not, or are orphaned (see --prune).
+  {{.Spacer}}           Accepts package patterns.
+  {{.Name}} list      List available typewriters.
+  {{.Spacer}}           Type {{.Name}} list types to list types marked with +{{.Name}},
+  {{.Spacer}}           and the files they produce. Accepts package patterns.
+  {{.Name}} add       Add a third-party typewriter to the current package.
+  {{.Spacer}}           In module mode, path@version pins a version.
+  {{.Name}} remove    Remove a typewriter from the current package.
+  {{.Name}} get       Download and install imported typewriters. 
+  {{.Spacer}}           Optional flags from go get: [-d] [-fix] [-t] [-u].
+  {{.Name}} vet       Report malformed +{{.Name}} directives, and stale or hand-edited
+  {{.Spacer}}           generated files. Accepts packages, as for go vet.
+  {{.Name}} cache     Print the location of the cache of compiled custom runners.
+  {{.Spacer}}           Type {{.Name}} cache clean to remove it.
+  {{.Name}} watch     Watch the current directory for changes to files with +{{.Name}}
+  {{.Spacer}}           directives (or their type parameters), and run {{.Name}}.
+  {{.Spacer}}           Accepts package patterns; ./... watches the tree, including
+  {{.Spacer}}           new directories, and runs {{.Name}} only where files changed.
+  {{.Spacer}}           Optional flags as for {{.Name}}, and [-interval duration] to
+  {{.Spacer}}           wait after changes stop (default 1s), [-poll interval] to
+  {{.Spacer}}           scan for changes where file system events are unavailable.
+  {{.Name}} help      Print usage. Type {{.Name}} help <command> for details.
+
+Project settings may be given in gen.toml or gen.json, in the current directory
+or a parent; flags take precedence.
+
+Commands which run the go tool use go on PATH, or as set by $GEN_GO or -go.
+
+Further details are available at http://clipperhouse.github.io/gen
+
+`))
+
+// usage prints the arguments and flags of a single command
+func usage(c Config, cmd string) error {
+	info := usageInfo{
+		Name:    commandName(cmd),
+		Args:    cmds[cmd].args,
+		Summary: cmds[cmd].summary,
+	}
+
+	fs := newFlagSet(cmd, &c)
+	fs.VisitAll(func(*flag.Flag) {
+		info.Flags = true
+	})
+
+	if err := usageTmpl.Execute(c.out, info); err != nil {
+		return err
+	}
+
+	if info.Flags {
+		fs.SetOutput(c.out)
+		fs.PrintDefaults()
+		fmt.Fprintln(c.out)
+	}
+
+	return nil
+}
+
+type usageInfo struct {
+	Name, Args, Summary string
+	Flags               bool
+}
+
+var usageTmpl = template.Must(template.New("usage").Parse(`
+Usage:
+  {{.Name}}{{if .Flags}} [flags]{{end}}{{if .Args}} {{.Args}}{{end}}
+
+{{.Summary}}
+{{if .Flags}}
+Flags:
+{{else}}
+{{end}}`))
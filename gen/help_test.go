@@ -1,4 +1,4 @@
-package main
+package gen
 
 import (
 	"bytes"
@@ -8,7 +8,7 @@ import (
 func TestHelp(t *testing.T) {
 	// use buffer instead of Stdout so help doesn't write to console
 	var b bytes.Buffer
-	c := defaultConfig
+	c := DefaultConfig
 	c.out = &b
 
 	if err := help(c); err != nil {
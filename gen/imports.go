@@ -1,4 +1,4 @@
-package main
+package gen
 
 // represents the default "built-in" typewriters
 import _ "github.com/clipperhouse/slice"
@@ -0,0 +1,314 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func list(c Config) error {
+	tws, err := listTypewriters(c)
+	if err != nil {
+		return err
+	}
+
+	sources, err := typewriterSources(c)
+	if err != nil {
+		return err
+	}
+
+	for i := range tws {
+		source, ok := sources[tws[i].Path]
+		if !ok && c.inProcess {
+			source = "linked in"
+		}
+		tws[i].Source = source
+	}
+
+	if c.json {
+		for i := range tws {
+			c.emit(event{Event: "typewriter", TypeWriter: &tws[i]})
+		}
+		return nil
+	}
+
+	fmt.Fprintln(c.out, "Installed typewriters:")
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+
+	for _, tw := range tws {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t(%s)\n", tw.Name, tw.Path, tw.Version, tw.Source)
+	}
+
+	return w.Flush()
+}
+
+// listTypewriters describes the typewriters imported by the current package, the standard ones or those of its
+// custom file, as reported by a runner; see typewritersOf
+func listTypewriters(c Config) ([]typewriterInfo, error) {
+	listFunc := func(c Config) error {
+		app, err := typewriter.NewApp("+gen")
+
+		if err != nil {
+			return err
+		}
+
+		return json.NewEncoder(c.out).Encode(typewritersOf(app))
+	}
+
+	var tws []typewriterInfo
+	if err := executeJSON(listFunc, c, listImports, listTmpl, &tws); err != nil {
+		return nil, err
+	}
+
+	return tws, nil
+}
+
+// listTypes describes the types marked with +gen in the current package: their tags, the constraints they satisfy
+// and the file each typewriter would write for them
+func listTypes(c Config) error {
+	// invalid tags are listed, rather than an error; they are what gen would reject
+	files, types, problems, err := generateUnchecked(c)
+	if err != nil {
+		return err
+	}
+
+	lines, err := problemLines(problems)
+	if err != nil {
+		return err
+	}
+
+	for i := range types {
+		for _, f := range files {
+			if f.Type != types[i].Name {
+				continue
+			}
+			if types[i].Files == nil {
+				types[i].Files = make(map[string]string)
+			}
+			types[i].Files[f.TypeWriter] = f.Name
+		}
+	}
+
+	if c.json {
+		c.emitTypes(types)
+		c.emitLines("diagnostic", "", strings.Join(lines, "\n"))
+		return nil
+	}
+
+	for _, t := range types {
+		name := t.Name
+		if t.Pointer {
+			name = "*" + name
+		}
+
+		fmt.Fprintf(c.out, "%s.%s\t// %s\n", t.Package, name, t.directive())
+
+		var constraints []string
+		if t.Comparable {
+			constraints = append(constraints, "comparable")
+		}
+		if t.Ordered {
+			constraints = append(constraints, "ordered")
+		}
+		if t.Numeric {
+			constraints = append(constraints, "numeric")
+		}
+		if len(constraints) == 0 {
+			constraints = append(constraints, "none")
+		}
+
+		fmt.Fprintf(c.out, "  constraints: %s\n", strings.Join(constraints, ", "))
+
+		// in the order of the tags
+		for _, tag := range t.Tags {
+			if name, ok := t.Files[tag.Name]; ok {
+				fmt.Fprintf(c.out, "  %s -> %s\n", tag.Name, name)
+			}
+		}
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintln(c.out, "Invalid tags, which gen will reject (or ignore, with -f):")
+		for _, line := range lines {
+			fmt.Fprintf(c.out, "  %s\n", line)
+		}
+	}
+
+	return nil
+}
+
+// typewriterSources describes where each typewriter, keyed by import path, was imported from: the standard set,
+// the custom file or the project configuration file. Others were linked in to gen; see Main.
+func typewriterSources(c Config) (map[string]string, error) {
+	sources := make(map[string]string)
+
+	if c.inProcess {
+		for imp := range stdImports {
+			sources[imp.Path] = "standard"
+		}
+		return sources, nil
+	}
+
+	imports, err := getTypewriterImports(c)
+	if err != nil {
+		return nil, err
+	}
+
+	source := "standard"
+
+	if _, err := os.Stat(c.customName); err == nil {
+		source = c.customName
+	} else if !c.defaultImports().Equal(stdImports) {
+		source = "configured"
+	}
+
+	for imp := range imports {
+		sources[imp.Path] = source
+	}
+
+	return sources, nil
+}
+
+// typewritersOf describes the typewriters in app; keep in sync with listTmpl
+func typewritersOf(app *typewriter.App) []typewriterInfo {
+	var tws []typewriterInfo
+
+	for _, tw := range app.TypeWriters {
+		info := typewriterInfo{
+			Name: tw.Name(),
+			Path: packagePath(tw),
+		}
+
+		info.Version = moduleVersion(info.Path)
+
+		tws = append(tws, info)
+	}
+
+	return tws
+}
+
+// packagePath returns the import path of the package declaring the type of v; keep in sync with listTmpl
+func packagePath(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath()
+}
+
+// moduleVersion returns the version of the module providing path, in the running program; keep in sync with listTmpl
+func moduleVersion(path string) string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var best, version string
+
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if path != m.Path && !strings.HasPrefix(path, m.Path+"/") || len(m.Path) <= len(best) {
+			continue
+		}
+
+		best, version = m.Path, m.Version
+
+		if r := m.Replace; r != nil {
+			version = r.Version
+			if len(version) == 0 {
+				version = "=> " + r.Path
+			}
+		}
+	}
+
+	return version
+}
+
+// listImports are the imports of listTmpl
+var listImports = typewriter.NewImportSpecSet(
+	typewriter.ImportSpec{Path: "encoding/json"},
+	typewriter.ImportSpec{Path: "os"},
+	typewriter.ImportSpec{Path: "reflect"},
+	typewriter.ImportSpec{Path: "runtime/debug"},
+	typewriter.ImportSpec{Path: "strings"},
+	typewriter.ImportSpec{Path: "github.com/clipperhouse/typewriter"},
+)
+
+var listTmpl = template.Must(template.New("list").Parse(`
+// keep in sync with typewriterInfo in events.go; field names are matched case-insensitively
+type typewriterInfo struct {
+	Name, Path, Version string
+}
+
+// keep in sync with typewritersOf in list.go
+func typewritersOf(app *typewriter.App) []typewriterInfo {
+	var tws []typewriterInfo
+
+	for _, tw := range app.TypeWriters {
+		info := typewriterInfo{
+			Name: tw.Name(),
+			Path: packagePath(tw),
+		}
+
+		info.Version = moduleVersion(info.Path)
+
+		tws = append(tws, info)
+	}
+
+	return tws
+}
+
+// keep in sync with packagePath in list.go
+func packagePath(v interface{}) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath()
+}
+
+// keep in sync with moduleVersion in list.go
+func moduleVersion(path string) string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var best, version string
+
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if path != m.Path && !strings.HasPrefix(path, m.Path+"/") || len(m.Path) <= len(best) {
+			continue
+		}
+
+		best, version = m.Path, m.Version
+
+		if r := m.Replace; r != nil {
+			version = r.Version
+			if len(version) == 0 {
+				version = "=> " + r.Path
+			}
+		}
+	}
+
+	return version
+}
+
+func main() {
+	app, err := typewriter.NewApp("+gen")
+
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+
+	json.NewEncoder(os.Stdout).Encode(typewritersOf(app))
+}
+`))
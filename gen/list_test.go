@@ -0,0 +1,130 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_list_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// use buffer instead of Stdout so we can inspect the results
+	var b bytes.Buffer
+	tool := newFakeModule()
+	tool.exec = `[{"name":"foo","path":"github.com/clipperhouse/foowriter","version":"v1.2.3"},` +
+		`{"name":"slice","path":"github.com/clipperhouse/slice"},{"name":"stringer","path":"github.com/clipperhouse/stringer"}]` + "\n"
+
+	c := DefaultConfig
+	c.out = &b
+	c.tool = tool
+	c.customName = "_gen.go"
+	c.cacheDir = filepath.Join(root, "cache")
+
+	err = inDir(root, func() error {
+		// standard
+		if err := list(c); err != nil {
+			return err
+		}
+
+		// 1 line for title + 2 standard typewriters (see imports.go)
+		if lines := bytes.Count(b.Bytes(), []byte("\n")); lines != 3 {
+			t.Errorf("standard list should output 3 lines, got %v", lines)
+		}
+
+		if !strings.Contains(b.String(), "github.com/clipperhouse/slice") || !strings.Contains(b.String(), "(standard)") {
+			t.Errorf("standard list should show the import path and source of slice, got:\n%s", b.String())
+		}
+
+		// clear out the buffer
+		b.Reset()
+
+		// create a custom typewriter import file
+		if err := add(c, "github.com/clipperhouse/foowriter"); err != nil {
+			return err
+		}
+
+		b.Reset()
+
+		// custom file now exists
+		if err := list(c); err != nil {
+			return err
+		}
+
+		// a runner was built & executed
+		for _, call := range []string{"go get github.com/clipperhouse/foowriter@v1.2.3", "go build", "exec"} {
+			if !tool.called(call) {
+				t.Errorf("list should run %q, got %v", call, tool.calls)
+			}
+		}
+
+		// 1 line for title + 3 custom typewriters
+		if lines := bytes.Count(b.Bytes(), []byte("\n")); lines != 4 {
+			t.Errorf("custom list should output 4 lines, got %v:\n%s", lines, b.String())
+		}
+
+		if s := "github.com/clipperhouse/foowriter  v1.2.3  (_gen.go)"; !strings.Contains(b.String(), s) {
+			t.Errorf("custom list should show %q, got:\n%s", s, b.String())
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestListTypes(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_list_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go": "package a\n\n// +gen * slice:\"Where,GroupBy[string]\"\ntype A int\n\n// +gen slice:\"Any\"\ntype B struct{ f []int }\n\n// +gen slice:\"-Any\"\ntype C int\n\n// +gen slcie:\"Where\"\ntype D int\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+
+	err = inDir(root, func() error {
+		if err := listTypes(c); err != nil {
+			return err
+		}
+
+		for _, s := range []string{
+			// constraints apply to *A, as generated
+			"a.*A\t// +gen * slice:\"Where,GroupBy[string]\"\n  constraints: comparable\n  slice -> a_slice.go\n",
+			"a.B\t// +gen slice:\"Any\"\n  constraints: none\n  slice -> b_slice.go\n",
+			"a.C\t// +gen slice:\"-Any\"\n  constraints: comparable, ordered, numeric\n",
+			// invalid tags are listed, not an error
+			"a.D\t// +gen slcie:\"Where\"\n  constraints: comparable, ordered, numeric\n",
+			"Invalid tags, which gen will reject (or ignore, with -f):\n  a.go:12:9: unknown typewriter \"slcie\"\n",
+		} {
+			if !strings.Contains(b.String(), s) {
+				t.Errorf("list types should output %q, got:\n%s", s, b.String())
+			}
+		}
+
+		// nothing is written
+		if _, err := os.Stat("a_slice.go"); err == nil {
+			t.Error("list types should not write files")
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
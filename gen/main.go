@@ -0,0 +1,397 @@
+// Package gen implements the gen tool, for type-driven code generation for Go. Details and docs are available at
+// https://clipperhouse.github.io/gen.
+//
+// The gen command is a thin wrapper around Main. Teams may build their own, with further typewriters linked in:
+//
+//	package main
+//
+//	import (
+//		"github.com/clipperhouse/gen/gen"
+//		"example.com/foowriter"
+//	)
+//
+//	func main() {
+//		gen.Main(gen.DefaultConfig, foowriter.New())
+//	}
+//
+// Such a binary runs its typewriters in-process, rather than compiling a runner from _gen.go.
+//
+// To generate files from other Go programs, such as build tools, see Run. To check directives and generated files
+// with go vet or gopls, see Analyzer.
+package gen
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// Main runs the gen command with os.Args, and exits on error. Any typewriters passed are registered and run in-process,
+// along with the standard ones; custom imports files (_gen.go) and configured typewriters are then ignored.
+// Unset fields of c, such as all of those of Config{}, are as DefaultConfig.
+func Main(c Config, typewriters ...typewriter.Interface) {
+	var err error
+
+	c = c.withDefaults()
+
+	defer func() {
+		if err != nil {
+			if !exitStatusMsg.MatchString(err.Error()) {
+				os.Stderr.WriteString(err.Error() + "\n")
+			}
+			os.Exit(1)
+		}
+	}()
+
+	cleanupOnSignal()
+
+	for _, tw := range typewriters {
+		if err = typewriter.Register(tw); err != nil {
+			return
+		}
+	}
+
+	if len(typewriters) > 0 {
+		c.inProcess = true
+	}
+
+	// invoked by gen vet, as go vet's vet tool
+	if isVetTool(os.Args[1:]) {
+		vetTool(c)
+	}
+
+	err = runMain(c, os.Args)
+}
+
+var exitStatusMsg = regexp.MustCompile("^exit status \\d+$")
+
+func runMain(c Config, args []string) error {
+	// flags are bound to c.Config, don't mutate the default
+	conf := *c.Config
+	c.Config = &conf
+
+	// the go tool may be overridden by environment, or by the -go flag
+	if path := os.Getenv("GEN_GO"); len(path) > 0 {
+		c.tool = goToolchain{path}
+	}
+
+	// find the command first, so that help works, and usage errors are reported, despite a broken configuration file
+	probe := c
+	probeConf := *c.Config
+	probe.Config = &probeConf
+
+	cmd, tail, err := parseArgs(&probe, args)
+
+	if err == flag.ErrHelp {
+		return usage(c, cmd)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if cmd == "help" {
+		return help(c, tail...)
+	}
+
+	// flags take precedence over the project configuration file, so are parsed after it is applied
+	if err := loadConfigFile(&c); err != nil {
+		return err
+	}
+
+	if cmd, tail, err = parseArgs(&c, args); err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "":
+		// simply typed 'gen'; run is the default command
+		return forPatterns(c, tail, run)
+	case "add":
+		return add(c, tail...)
+	case "cache":
+		return cache(c, tail...)
+	case "check":
+		return forPatterns(c, tail, check)
+	case "get":
+		return get(c, tail...)
+	case "list":
+		if len(tail) > 0 {
+			// listArgs has ensured tail[0] is types
+			return forPatterns(c, tail[1:], listTypes)
+		}
+		return list(c)
+	case "remove":
+		return remove(c, tail...)
+	case "vet":
+		return vet(c, tail...)
+	case "watch":
+		return watch(c, tail...)
+	default:
+		return help(c, tail...)
+	}
+}
+
+var s = struct{}{}
+
+// command describes the flags and arguments accepted by a gen command
+type command struct {
+	// args describes positional arguments, for usage
+	args string
+	// summary describes the command, for usage
+	summary string
+	// flags declares the command's flags, typically bound to fields of c
+	flags func(fs *flag.FlagSet, c *Config)
+	// passFlags indicates that flags are passed through to the go tool, ahead of positional arguments
+	passFlags bool
+	// validate checks positional arguments
+	validate func(tail []string) error
+}
+
+// cmds are keyed by name; run, the default, has no name
+var cmds = map[string]command{
+	"": {
+		args:     "[packages]",
+		summary:  "Generate files for types marked with +gen. Package patterns, such as ./..., run gen in each matching package.",
+		flags:    runFlags,
+		validate: patternArgs,
+	},
+	"add": {
+		args:     "<import path>[@version]...",
+		summary:  "Add a third-party typewriter to the current package. In module mode, the version (default latest) is pinned in the custom file.",
+		flags:    goFlag,
+		validate: anyArgs,
+	},
+	"cache": {
+		args:     "[clean]",
+		summary:  "Print the location of the cache of compiled custom typewriter runners or, with clean, remove it.",
+		validate: cacheArgs,
+	},
+	"check": {
+		args:    "[packages]",
+		summary: "Verify that generated files are up to date, printing a diff of any that are not, or that are no longer produced by any +gen directive.",
+		flags: func(fs *flag.FlagSet, c *Config) {
+			fs.BoolVar(&c.IgnoreTypeCheckErrors, "f", c.IgnoreTypeCheckErrors, "ignore type check errors and invalid +gen tags, warning of them")
+			jsonFlag(fs, c)
+			goFlag(fs, c)
+		},
+		validate: patternArgs,
+	},
+	"get": {
+		args:    "[import path]...",
+		summary: "Download and install imported typewriters, at their pinned versions in module mode. Flags are passed to go get.",
+		flags: func(fs *flag.FlagSet, c *Config) {
+			fs.Bool("d", false, "download only, don't install")
+			fs.Bool("fix", false, "run the fix tool on downloaded packages")
+			fs.Bool("t", false, "also download packages required to build tests")
+			fs.Bool("u", false, "update packages and their dependencies")
+			goFlag(fs, c)
+		},
+		passFlags: true,
+		validate:  anyArgs,
+	},
+	"help": {
+		args:    "[command]",
+		summary: "Print usage, for gen or for a single command.",
+		// validate is assigned in init, helpArgs refers to cmds
+	},
+	"list": {
+		args: "[types [packages]]",
+		summary: "List available typewriters, with their import paths, module versions and where they were imported from. " +
+			"With types, list the types marked with +gen instead, with their tags, the constraints they satisfy and the files which would be written for them.",
+		flags: func(fs *flag.FlagSet, c *Config) {
+			jsonFlag(fs, c)
+			goFlag(fs, c)
+		},
+		validate: listArgs,
+	},
+	"remove": {
+		args:     "<import path>...",
+		summary:  "Remove a typewriter from the current package, the inverse of add.",
+		flags:    goFlag,
+		validate: anyArgs,
+	},
+	"vet": {
+		args:    "[packages]",
+		summary: "Report malformed +gen directives, directives on other than types, and stale or hand-edited generated files, by running go vet with gen's analyzer. Packages are as for go vet.",
+		flags: func(fs *flag.FlagSet, c *Config) {
+			jsonFlag(fs, c)
+			goFlag(fs, c)
+		},
+		validate: anyArgs,
+	},
+	"watch": {
+		args:    "[packages]",
+		summary: "Watch the current directory (or packages) for changes to files with +gen directives, or declaring types used as their type parameters, and run gen. Generated files are ignored. Patterns ending in /... watch the tree beneath, including new directories, running gen only in packages whose files changed. Where file system events are unavailable, changes are found by polling.",
+		flags: func(fs *flag.FlagSet, c *Config) {
+			runFlags(fs, c)
+			fs.DurationVar(&c.watchInterval, "interval", c.watchInterval, "wait `duration` after changes stop before running gen; also watch.interval in gen.toml")
+			fs.DurationVar(&c.pollInterval, "poll", c.pollInterval, "scan for changes every `interval`, for file systems without change events; also watch.poll in gen.toml")
+		},
+		validate: patternArgs,
+	},
+}
+
+func init() {
+	help := cmds["help"]
+	help.validate = helpArgs
+	cmds["help"] = help
+}
+
+func runFlags(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.IgnoreTypeCheckErrors, "f", c.IgnoreTypeCheckErrors, "ignore type check errors and invalid +gen tags, warning of them")
+	fs.BoolVar(&c.dryRun, "n", c.dryRun, "print generated files rather than writing them")
+	fs.BoolVar(&c.dryRun, "dry-run", c.dryRun, "same as -n")
+	fs.BoolVar(&c.prune, "prune", c.prune, "remove generated files which are no longer produced by any +gen directive")
+	fs.BoolVar(&c.yes, "y", c.yes, "with -prune, remove files without asking, such as in scripts")
+	jsonFlag(fs, c)
+	goFlag(fs, c)
+}
+
+// jsonFlag is for commands which can write events; see event
+func jsonFlag(fs *flag.FlagSet, c *Config) {
+	fs.BoolVar(&c.json, "json", c.json, "write JSON events, one per line, in place of the usual output")
+}
+
+// goFlag is for commands which run the go tool
+func goFlag(fs *flag.FlagSet, c *Config) {
+	fs.Var(toolFlag{&c.tool}, "go", "the go `tool` to use, rather than go on PATH; also $GEN_GO")
+}
+
+func anyArgs(tail []string) error {
+	return nil
+}
+
+func noArgs(tail []string) error {
+	if len(tail) > 0 {
+		return fmt.Errorf("unexpected argument(s) %v", tail)
+	}
+	return nil
+}
+
+func cacheArgs(tail []string) error {
+	if len(tail) > 1 || (len(tail) == 1 && tail[0] != "clean") {
+		return fmt.Errorf("unexpected argument(s) %v", tail)
+	}
+	return nil
+}
+
+func listArgs(tail []string) error {
+	if len(tail) == 0 {
+		return nil
+	}
+	if tail[0] != "types" {
+		return fmt.Errorf("unexpected argument(s) %v", tail)
+	}
+	return patternArgs(tail[1:])
+}
+
+func patternArgs(tail []string) error {
+	for _, a := range tail {
+		if !isPattern(a) {
+			// such as an import path, which unlike the go tool gen doesn't resolve
+			if strings.ContainsRune(a, '/') {
+				return fmt.Errorf("%s is not a directory; packages are directories, such as ./foo, or patterns such as ./foo/...", a)
+			}
+			return fmt.Errorf("unknown command %q", a)
+		}
+	}
+	return nil
+}
+
+func helpArgs(tail []string) error {
+	if len(tail) > 1 {
+		return fmt.Errorf("unexpected argument(s) %v", tail[1:])
+	}
+	if len(tail) == 1 {
+		if _, ok := lookup(tail[0]); !ok {
+			return fmt.Errorf("unknown command %q", tail[0])
+		}
+	}
+	return nil
+}
+
+// lookup finds a command by name, allowing 'run' for the default command
+func lookup(name string) (string, bool) {
+	if name == "run" {
+		return "", true
+	}
+	_, ok := cmds[name]
+	return name, ok && name != ""
+}
+
+// commandName returns the name of cmd as typed, such as 'gen watch'
+func commandName(cmd string) string {
+	name := filepath.Base(os.Args[0])
+	if len(cmd) > 0 {
+		name += " " + cmd
+	}
+	return name
+}
+
+func usageError(cmd string, err error) error {
+	hint := commandName("help")
+	if len(cmd) > 0 {
+		hint += " " + cmd
+	}
+	return fmt.Errorf("%s: %v; type %s for usage", commandName(cmd), err, hint)
+}
+
+// newFlagSet returns the flags for cmd, bound to c
+func newFlagSet(cmd string, c *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet(commandName(cmd), flag.ContinueOnError)
+
+	// errors are returned to the caller, usage is printed by help
+	fs.SetOutput(ioutil.Discard)
+
+	if flags := cmds[cmd].flags; flags != nil {
+		flags(fs, c)
+	}
+
+	return fs
+}
+
+// parseArgs determines the command, setting flags on c. It returns flag.ErrHelp if -h was requested.
+func parseArgs(c *Config, args []string) (cmd string, tail []string, err error) {
+	args = args[1:] // arg[0] is 'gen'
+
+	if len(args) > 0 {
+		if _, ok := cmds[args[0]]; ok && args[0] != "" {
+			cmd, args = args[0], args[1:]
+		}
+	}
+
+	fs := newFlagSet(cmd, c)
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return cmd, nil, err
+		}
+		return cmd, nil, usageError(cmd, err)
+	}
+
+	if cmds[cmd].passFlags {
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "go" {
+				// gen's own
+				return
+			}
+			// with its value, so that -d=false is not passed as -d
+			tail = append(tail, "-"+f.Name+"="+f.Value.String())
+		})
+	}
+
+	tail = append(tail, fs.Args()...)
+
+	if err := cmds[cmd].validate(fs.Args()); err != nil {
+		return cmd, nil, usageError(cmd, err)
+	}
+
+	return cmd, tail, nil
+}
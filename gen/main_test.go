@@ -0,0 +1,167 @@
+package gen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+type parseTest struct {
+	text  string
+	cmd   string
+	force bool
+	dry   bool
+	tail  int  //length
+	err   bool //exists
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []parseTest{
+		parseTest{"gen", "", false, false, 0, false},
+		parseTest{"gen -f", "", true, false, 0, false},
+		parseTest{"gen -n", "", false, true, 0, false},
+		parseTest{"gen --dry-run ./...", "", false, true, 1, false},
+		parseTest{"gen -dry-run", "", false, true, 0, false},
+		parseTest{"gen -f -n", "", true, true, 0, false},
+		parseTest{"gen -prune -y", "", false, false, 0, false},
+		parseTest{"gen yadda", "", false, false, 0, true},           // unknown command
+		parseTest{"gen -bar", "", false, false, 0, true},            // unknown flag
+		parseTest{"gen -h", "", false, false, 0, true},              // help
+		parseTest{"gen ./...", "", false, false, 1, false},          // package pattern is ok
+		parseTest{"gen -f ./foo ./bar", "", true, false, 2, false},  // package patterns are ok
+		parseTest{"gen ./... yadda", "", false, false, 0, true},     // unknown command
+		parseTest{"gen testdata/...", "", false, false, 1, false},   // a pattern needn't begin with .
+		parseTest{"gen example.com/foo", "", false, false, 0, true}, // import paths are not resolved
+		parseTest{"gen -f watch", "", true, false, 0, true},         // command must come first
+		parseTest{"gen add", "add", false, false, 0, false},
+		parseTest{"gen add foo bar", "add", false, false, 2, false}, // tail is ok
+		parseTest{"gen add -f", "add", false, false, 0, true},       // force is not ok
+		parseTest{"gen add -n", "add", false, false, 0, true},       // dry run is not ok
+		parseTest{"gen add -h", "add", false, false, 0, true},       // help
+		parseTest{"gen cache", "cache", false, false, 0, false},
+		parseTest{"gen cache clean", "cache", false, false, 1, false},
+		parseTest{"gen cache foo", "cache", false, false, 0, true},       // tail is not ok
+		parseTest{"gen cache clean foo", "cache", false, false, 0, true}, // tail is not ok
+		parseTest{"gen check", "check", false, false, 0, false},
+		parseTest{"gen check -n", "check", false, false, 0, true},     // dry run is not ok
+		parseTest{"gen check ./...", "check", false, false, 1, false}, // package pattern is ok
+		parseTest{"gen check foo", "check", false, false, 0, true},    // tail is not ok
+		parseTest{"gen check -f", "check", true, false, 0, false},     // force is ok
+		parseTest{"gen get", "get", false, false, 0, false},
+		parseTest{"gen get foo bar", "get", false, false, 2, false},   // tail is ok
+		parseTest{"gen get -d", "get", false, false, 1, false},        // go get flag is passed through
+		parseTest{"gen get -d -u foo", "get", false, false, 3, false}, // go get flags are passed through
+		parseTest{"gen get -f", "get", false, false, 0, true},         // force is not ok
+		parseTest{"gen help", "help", false, false, 0, false},
+		parseTest{"gen help watch", "help", false, false, 1, false},  // command is ok
+		parseTest{"gen help run", "help", false, false, 1, false},    // run is ok
+		parseTest{"gen help yadda", "help", false, false, 0, true},   // unknown command
+		parseTest{"gen help foo bar", "help", false, false, 0, true}, // tail is not ok
+		parseTest{"gen help -f", "help", false, false, 0, true},      // force is not ok
+		parseTest{"gen list", "list", false, false, 0, false},
+		parseTest{"gen list foo bar", "list", false, false, 0, true}, // tail is not ok
+		parseTest{"gen list -f", "list", false, false, 0, true},      // force is not ok
+		parseTest{"gen list ./...", "list", false, false, 0, true},   // package pattern is not ok
+		parseTest{"gen list types", "list", false, false, 1, false},
+		parseTest{"gen list types ./...", "list", false, false, 2, false}, // package patterns are ok after types
+		parseTest{"gen list types foo", "list", false, false, 0, true},    // unknown argument
+		parseTest{"gen remove -go /opt/go/bin/go foo", "remove", false, false, 1, false},
+		parseTest{"gen vet", "vet", false, false, 0, false},
+		parseTest{"gen vet ./... foo", "vet", false, false, 2, false}, // packages are as for go vet
+		parseTest{"gen vet -f", "vet", false, false, 0, true},         // force is not ok
+		parseTest{"gen watch", "watch", false, false, 0, false},
+		parseTest{"gen watch foo bar", "watch", false, false, 0, true},  // tail is not ok
+		parseTest{"gen watch -f", "watch", true, false, 0, false},       // force is ok
+		parseTest{"gen watch -n", "watch", false, true, 0, false},       // dry run is ok
+		parseTest{"gen watch ./...", "watch", false, false, 1, false},   // package pattern is ok
+		parseTest{"gen watch ./... -f", "watch", false, false, 0, true}, // flags must precede patterns
+		parseTest{"gen watch -interval 500ms ./...", "watch", false, false, 1, false},
+		parseTest{"gen watch -interval soon", "watch", false, false, 0, true}, // not a duration
+		parseTest{"gen -interval 500ms", "", false, false, 0, true},           // watch only
+		parseTest{"gen watch -poll 2s", "watch", false, false, 0, false},
+	}
+
+	for i, test := range tests {
+		c := DefaultConfig
+		c.Config = &typewriter.Config{}
+
+		cmd, tail, err := parseArgs(&c, strings.Split(test.text, " "))
+		if cmd != test.cmd {
+			t.Errorf("tests[%d]: cmd should be %q, got %q", i, test.cmd, cmd)
+		}
+		if c.IgnoreTypeCheckErrors != test.force {
+			t.Errorf("tests[%d]: force should be %v, got %v", i, test.force, c.IgnoreTypeCheckErrors)
+		}
+		if c.dryRun != test.dry {
+			t.Errorf("tests[%d]: dry should be %v, got %v", i, test.dry, c.dryRun)
+		}
+		if len(tail) != test.tail {
+			t.Errorf("tests[%d]: len(tail) should be %v, got %v", i, test.tail, len(tail))
+		}
+		if (err != nil) != test.err {
+			t.Errorf("tests[%d]: err existence should be %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestPassFlags(t *testing.T) {
+	c := DefaultConfig
+	c.Config = &typewriter.Config{}
+
+	_, tail, err := parseArgs(&c, strings.Split("gen get -d=false -u foo", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := strings.Join(tail, " "), "-d=false -u=true foo"; got != want {
+		t.Errorf("tail should be %q, got %q", want, got)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for cmd := range cmds {
+		var b bytes.Buffer
+		c := DefaultConfig
+		c.out = &b
+
+		if err := usage(c, cmd); err != nil {
+			t.Error(err)
+		}
+
+		if !strings.Contains(b.String(), cmds[cmd].summary) {
+			t.Errorf("usage for %q should include summary, got:\n%s", cmd, b.String())
+		}
+
+		if cmds[cmd].flags != nil && !strings.Contains(b.String(), "Flags:") {
+			t.Errorf("usage for %q should include flags, got:\n%s", cmd, b.String())
+		}
+	}
+}
+
+func TestZeroConfig(t *testing.T) {
+	var b bytes.Buffer
+
+	// as Main, which teams may call with Config{}
+	c := Config{out: &b}.withDefaults()
+
+	if err := runMain(c, []string{"gen", "help", "list"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), cmds["list"].summary) {
+		t.Errorf("help for list should be printed, got:\n%s", b.String())
+	}
+
+	// set fields are kept
+	c = Config{Config: &typewriter.Config{IgnoreTypeCheckErrors: true}}.withDefaults()
+
+	if !c.IgnoreTypeCheckErrors {
+		t.Error("IgnoreTypeCheckErrors should be kept")
+	}
+
+	if c.customName != DefaultConfig.customName || c.tool == nil || c.ctx == nil {
+		t.Errorf("unset fields should be as DefaultConfig, got %+v", c)
+	}
+}
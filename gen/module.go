@@ -0,0 +1,228 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// listModule is the subset of module information reported by go list -json
+type listModule struct {
+	Path, Version, Dir string
+	Main               bool
+	Replace            *listModule
+}
+
+// runnerDeps describes what the runner for a custom file depends on, as resolved in the current package; see runnerModules
+type runnerDeps struct {
+	// mods are the modules providing the custom imports and typewriter, and their dependencies; none in GOPATH mode
+	mods []listModule
+	// gets are arguments to go get, for the runner's module
+	gets []string
+	// dirs are the directories of packages built from source which can change without a change of module version:
+	// those of the main module and of modules replaced by a directory or, in GOPATH mode, all but the standard library
+	dirs []string
+}
+
+// runnerModules returns the dependencies of the runner for the custom imports and typewriter, with one call of go list.
+// Modules are returned only if the go tool is in module mode.
+//
+// Imports pinned to a version in the custom file (see parseImports), and those which the current module
+// does not provide, are instead returned as arguments to go get, for the runner's module.
+func runnerModules(c Config, custom []byte) (runnerDeps, error) {
+	var deps runnerDeps
+
+	modules, err := moduleMode(c)
+	if err != nil {
+		return deps, err
+	}
+
+	imports, versions, err := parseImports(custom)
+	if err != nil {
+		return deps, err
+	}
+
+	paths := []string{"github.com/clipperhouse/typewriter"}
+
+	for _, imp := range imports.ToSlice() {
+		if v, ok := versions[imp.Path]; ok && modules {
+			deps.gets = append(deps.gets, imp.Path+"@"+v)
+			continue
+		}
+		paths = append(paths, imp.Path)
+	}
+
+	out, err := c.goOutput(append([]string{"list", "-e", "-deps", "-json"}, paths...)...)
+	if err != nil {
+		return deps, err
+	}
+
+	seen := make(map[string]struct{})
+
+	d := json.NewDecoder(bytes.NewReader(out))
+
+	for {
+		var p struct {
+			ImportPath, Dir string
+			Standard        bool
+			DepOnly         bool
+			Module          *listModule
+			Error           *struct{ Err string }
+		}
+
+		if err := d.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return deps, err
+		}
+
+		if p.Standard {
+			continue
+		}
+
+		if m := p.Module; len(p.Dir) > 0 && (m == nil || m.Main || (m.Replace != nil && len(m.Replace.Version) == 0)) {
+			deps.dirs = append(deps.dirs, p.Dir)
+		}
+
+		if !modules {
+			continue
+		}
+
+		// not provided by the current module, so get the latest
+		if p.Module == nil {
+			if p.Error != nil && !p.DepOnly {
+				deps.gets = append(deps.gets, p.ImportPath)
+			}
+			continue
+		}
+
+		if _, ok := seen[p.Module.Path]; ok {
+			continue
+		}
+
+		seen[p.Module.Path] = s
+		deps.mods = append(deps.mods, *p.Module)
+	}
+
+	sort.Strings(deps.dirs)
+
+	return deps, nil
+}
+
+// moduleMode reports whether the go tool is in module mode, in the current directory
+func moduleMode(c Config) (bool, error) {
+	gomod, err := c.goOutput("env", "GOMOD")
+	if err != nil {
+		return false, err
+	}
+
+	return len(bytes.TrimSpace(gomod)) > 0, nil
+}
+
+// getScratchModule returns a new temp directory containing an empty module, in which to go get typewriters
+// without touching the user's go.mod. The caller should removeTempDir it.
+func getScratchModule() (string, error) {
+	dir, err := getTempDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gen-scratch\n"), 0666); err != nil {
+		removeTempDir(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// resolve fetches the typewriter at path, at version (a version, or a query such as latest), returning the version
+// of the module providing it
+func resolve(c Config, path, version string) (string, error) {
+	if len(version) == 0 {
+		version = "latest"
+	}
+
+	scratch, err := getScratchModule()
+	if err != nil {
+		return "", err
+	}
+	defer removeTempDir(scratch)
+
+	if err := c.goRun(scratch, []string{"GOFLAGS=-mod=mod"}, "get", path+"@"+version); err != nil {
+		return "", err
+	}
+
+	out, err := c.goOutputDir(scratch, "list", "-f", "{{.Module.Version}}", path)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := strings.TrimSpace(string(out))
+	if len(resolved) == 0 {
+		return "", fmt.Errorf("%s: no module version found", path)
+	}
+
+	return resolved, nil
+}
+
+// goMod returns a go.mod requiring mods at the versions resolved in the user's module, with the same replacements
+func goMod(mods []listModule) []byte {
+	var b bytes.Buffer
+
+	b.WriteString("// Generated by gen, to build custom typewriters\n\nmodule gen-runner\n\n")
+
+	for _, m := range mods {
+		version := m.Version
+		if len(version) == 0 {
+			// the main module, or one replaced by a directory, has no version
+			version = "v0.0.0"
+		}
+
+		fmt.Fprintf(&b, "require %s %s\n", m.Path, version)
+
+		switch {
+		case m.Main:
+			fmt.Fprintf(&b, "replace %s => %q\n", m.Path, m.Dir)
+		case m.Replace != nil && len(m.Replace.Version) == 0:
+			fmt.Fprintf(&b, "replace %s => %q\n", m.Path, m.Replace.Dir)
+		case m.Replace != nil:
+			fmt.Fprintf(&b, "replace %s => %s %s\n", m.Path, m.Replace.Path, m.Replace.Version)
+		}
+	}
+
+	return b.Bytes()
+}
+
+// writeModule sets up dir as a module for building the custom runner. The user's go.sum is copied, so
+// that the (already verified) modules are not looked up again.
+func writeModule(c Config, dir string, mods []listModule) error {
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), goMod(mods), 0666); err != nil {
+		return err
+	}
+
+	gomod, err := c.goOutput("env", "GOMOD")
+	if err != nil {
+		return err
+	}
+
+	path := strings.TrimSpace(string(gomod))
+	if len(path) == 0 || path == os.DevNull {
+		return nil
+	}
+
+	sum, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), "go.sum"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, "go.sum"), sum, 0666)
+}
@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunnerModules(t *testing.T) {
+	custom := []byte("package main\n\nimport _ \"github.com/clipperhouse/slice\"\n")
+
+	deps, err := runnerModules(DefaultConfig, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]string)
+	for _, m := range deps.mods {
+		found[m.Path] = m.Version
+	}
+
+	// as required by this module's go.mod
+	for _, path := range []string{"github.com/clipperhouse/slice", "github.com/clipperhouse/typewriter", "golang.org/x/tools"} {
+		if len(found[path]) == 0 {
+			t.Errorf("runnerModules should include %s with a version, got %v", path, found)
+		}
+	}
+}
+
+func TestRunnerModulesPinned(t *testing.T) {
+	custom := []byte("package main\n\nimport _ \"github.com/clipperhouse/slice\" // v1.0.0\n")
+
+	deps, err := runnerModules(DefaultConfig, custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pinned imports are got at their version, rather than as resolved in this module
+	if len(deps.gets) != 1 || deps.gets[0] != "github.com/clipperhouse/slice@v1.0.0" {
+		t.Errorf("runnerModules should get the pinned version, got %v", deps.gets)
+	}
+
+	for _, m := range deps.mods {
+		if m.Path == "github.com/clipperhouse/slice" {
+			t.Errorf("runnerModules should not include pinned %s", m.Path)
+		}
+	}
+}
+
+func TestGoMod(t *testing.T) {
+	mods := []listModule{
+		{Path: "example.com/main", Dir: "/src/main", Main: true},
+		{Path: "example.com/a", Version: "v1.2.3"},
+		{Path: "example.com/b", Version: "v1.0.0", Replace: &listModule{Path: "example.com/fork", Version: "v1.0.1"}},
+		{Path: "example.com/c", Version: "v1.0.0", Replace: &listModule{Path: "../c", Dir: "/src/c"}},
+	}
+
+	expected := []string{
+		"module gen-runner",
+		"require example.com/main v0.0.0\nreplace example.com/main => \"/src/main\"",
+		"require example.com/a v1.2.3\n",
+		"require example.com/b v1.0.0\nreplace example.com/b => example.com/fork v1.0.1",
+		"require example.com/c v1.0.0\nreplace example.com/c => \"/src/c\"",
+	}
+
+	gomod := string(goMod(mods))
+
+	for _, e := range expected {
+		if !strings.Contains(gomod, e) {
+			t.Errorf("go.mod should contain %q, got:\n%s", e, gomod)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isGenerated reports whether the named file begins with the byline written by gen, such as:
+//
+//	// Generated by: gen
+//	// TypeWriter: slice
+//	// Directive: +gen on MyType
+func isGenerated(name string) (bool, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+
+	return hasByline(src), nil
+}
+
+// hasByline reports whether src begins with the byline written by gen; see isGenerated
+func hasByline(src []byte) bool {
+	lines := bytes.SplitN(src, []byte("\n"), 4)
+	if len(lines) < 3 {
+		return false
+	}
+
+	for i, prefix := range []string{"// Generated by: ", "// TypeWriter: ", "// Directive: "} {
+		if !bytes.HasPrefix(lines[i], []byte(prefix)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasGenerated reports whether dir holds any Go file generated by gen, and not excluded; see isGenerated
+func hasGenerated(c Config, dir string) bool {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, fi := range infos {
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(fi.Name(), ".go") || !sourceFile(fi) || c.excludes(fi.Name()) {
+			continue
+		}
+
+		if generated, _ := isGenerated(filepath.Join(dir, fi.Name())); generated {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orphans returns the names of files in the current directory which were generated by gen, but are not among files.
+// These are typically left behind when a +gen directive (or a tag, or a typewriter) is removed. Files excluded by the
+// project configuration are ignored.
+func orphans(c Config, files []file) ([]string, error) {
+	current := make(map[string]struct{})
+	for _, f := range files {
+		current[f.Name] = s
+	}
+
+	infos, err := ioutil.ReadDir("./")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+
+	for _, fi := range infos {
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(fi.Name(), ".go") || c.excludes(fi.Name()) {
+			continue
+		}
+
+		if _, ok := current[fi.Name()]; ok {
+			continue
+		}
+
+		generated, err := isGenerated(fi.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if generated {
+			result = append(result, fi.Name())
+		}
+	}
+
+	return result, nil
+}
+
+// prune lists orphaned files or, with the prune flag, removes them; see orphans.
+// Removal is confirmed at a terminal or, with the yes flag, not asked; otherwise, prune removes nothing and
+// returns an error.
+func prune(c Config, files []file) error {
+	names, err := orphans(c, files)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	if c.json {
+		for _, name := range names {
+			c.emit(event{Event: "orphan", File: name})
+		}
+	} else {
+		fmt.Fprintln(c.out, "Generated files no longer produced by any +gen directive:")
+		for _, name := range names {
+			fmt.Fprintf(c.out, "  %s\n", name)
+		}
+	}
+
+	if !c.prune || c.dryRun {
+		if !c.json {
+			fmt.Fprintln(c.out, "Use the --prune flag to remove them.")
+		}
+		for _, name := range names {
+			c.warn(abs(name) + " is no longer produced by any +gen directive")
+		}
+		return nil
+	}
+
+	if !c.yes {
+		// the prompt would be lost amid JSON output
+		if !isTerminal(c.in) || c.json {
+			return fmt.Errorf("%d generated file(s) not removed without confirmation; use the -y flag with -prune to remove them without asking", len(names))
+		}
+
+		fmt.Fprintf(c.out, "Remove %d file(s)? [y/N] ", len(names))
+
+		answer, _ := bufio.NewReader(c.in).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+
+		if answer != "y" && answer != "yes" {
+			return nil
+		}
+	}
+
+	for _, name := range names {
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+		c.removed(name)
+
+		if c.json {
+			c.emit(event{Event: "remove", File: name})
+			continue
+		}
+		fmt.Fprintf(c.out, "Removed %s\n", name)
+	}
+
+	return nil
+}
+
+// isTerminal reports whether r is a terminal, as opposed to a pipe, file or device such as /dev/null
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+
+	return isTerminalFd(f.Fd())
+}
@@ -0,0 +1,131 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_orphans_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go":        "package a\n\n// +gen slice:\"Where\"\ntype A int\n\n// +gen slice:\"Any\"\ntype B int\n",
+		"handmade.go": "package a\n\n// Generated by: hand\nfunc C() {}\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.in = strings.NewReader("")
+
+	err = inDir(root, func() error {
+		if err := run(c); err != nil {
+			return err
+		}
+
+		if b.Len() > 0 {
+			t.Errorf("run should not report orphans, got:\n%s", b.String())
+		}
+
+		// remove the directive on B
+		writeTree(t, root, map[string]string{
+			"a.go": "package a\n\n// +gen slice:\"Where\"\ntype A int\n\ntype B int\n",
+		})
+
+		names, err := orphans(c, nil)
+		if err != nil {
+			return err
+		}
+
+		if len(names) != 2 {
+			t.Errorf("orphans should find 2 generated files, got %v", names)
+		}
+
+		// without prune, orphans are listed but not removed
+		if err := run(c); err != nil {
+			return err
+		}
+
+		if !strings.Contains(b.String(), "  b_slice.go\n") {
+			t.Errorf("run should list b_slice.go as an orphan, got:\n%s", b.String())
+		}
+
+		if _, err := os.Stat("b_slice.go"); err != nil {
+			t.Error("b_slice.go should not be removed without prune")
+		}
+
+		c.prune = true
+
+		// not a terminal, so removal can't be confirmed
+		if err := run(c); err == nil || !strings.Contains(err.Error(), "-y") {
+			t.Errorf("run -prune should fail without a terminal or -y, got %v", err)
+		}
+
+		if _, err := os.Stat("b_slice.go"); err != nil {
+			t.Error("b_slice.go should not be removed without confirmation")
+		}
+
+		c.yes = true
+
+		if err := run(c); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat("b_slice.go"); err == nil {
+			t.Error("b_slice.go should be removed with prune")
+		}
+
+		// remove the last directive
+		writeTree(t, root, map[string]string{
+			"a.go": "package a\n\ntype A int\n\ntype B int\n",
+		})
+
+		if err := run(c); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat("a_slice.go"); err == nil {
+			t.Error("a_slice.go should be removed with prune")
+		}
+
+		// nothing left to prune; no directives is an error, as usual
+		if err := run(c); err == nil {
+			t.Error("run without directives should be an error")
+		}
+
+		// not generated by gen
+		if _, err := os.Stat("handmade.go"); err != nil {
+			t.Error(err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	// a character device, but not a terminal; CI commonly runs with stdin from it
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if isTerminal(f) {
+		t.Errorf("%s should not be a terminal", os.DevNull)
+	}
+
+	if isTerminal(strings.NewReader("")) {
+		t.Error("a reader other than a file should not be a terminal")
+	}
+}
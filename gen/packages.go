@@ -0,0 +1,215 @@
+package gen
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// isPattern reports whether arg is a package pattern: a directory, such as ./foo or foo/bar, or a pattern ending in
+// /..., such as foo/..., for the tree beneath one. Paths beginning with . or / are patterns even if missing, for packages
+// to report as such.
+func isPattern(arg string) bool {
+	if strings.HasPrefix(arg, ".") || filepath.IsAbs(arg) || strings.HasSuffix(arg, "/...") {
+		return true
+	}
+
+	fi, err := os.Stat(arg)
+	return err == nil && fi.IsDir()
+}
+
+// packages resolves package patterns into a list of directories.
+//
+// A pattern ending in /... matches every directory beneath it containing a +gen directive, or a file generated by gen,
+// whose directives may have been removed, leaving it orphaned; any other pattern is treated as a single directory.
+func packages(c Config, patterns []string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]struct{})
+
+	add := func(dir string) {
+		dir = filepath.Clean(dir)
+		if _, ok := seen[dir]; ok {
+			return
+		}
+		seen[dir] = s
+		dirs = append(dirs, dir)
+	}
+
+	for _, pattern := range patterns {
+		if !strings.HasSuffix(pattern, "...") {
+			fi, err := os.Stat(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if !fi.IsDir() {
+				return nil, fmt.Errorf("%s is not a directory", pattern)
+			}
+			add(pattern)
+			continue
+		}
+
+		root := strings.TrimSuffix(pattern, "...")
+		if root == "" {
+			root = "."
+		}
+
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !fi.IsDir() {
+				return nil
+			}
+
+			if filepath.Clean(path) != filepath.Clean(root) && skipDir(fi.Name()) {
+				return filepath.SkipDir
+			}
+
+			if hasDirective(c, path, "+gen") || hasGenerated(c, path) {
+				add(path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dirs, nil
+}
+
+// skipDir reports whether a directory should be ignored when walking a tree, following the conventions of the go tool
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// hasDirective reports whether any Go file in dir which gen parses (see Config.parses) contains a comment beginning
+// with directive. Files that fail to parse are considered to have a directive, so that gen will report the error.
+func hasDirective(c Config, dir, directive string) bool {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, c.parses, parser.ParseComments)
+	if err != nil {
+		return true
+	}
+
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, g := range f.Comments {
+				for _, c := range g.List {
+					if isDirective(c.Text, directive) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// sourceFile reports whether the parser should read a file; unlike the go build tool, it does not ignore . and _ files
+func sourceFile(fi os.FileInfo) bool {
+	return !strings.HasPrefix(fi.Name(), "_") && !strings.HasPrefix(fi.Name(), ".")
+}
+
+// isDirective reports whether the text of a comment begins with directive, as typewriter finds them
+func isDirective(text, directive string) bool {
+	t := strings.TrimLeft(text, "/ ")
+	if !strings.HasPrefix(t, directive) {
+		return false
+	}
+
+	// must be eof or followed by a space
+	t = strings.TrimPrefix(t, directive)
+	return len(t) == 0 || t[0] == ' '
+}
+
+// chdirMu serializes the entry points which call inDir within a program, Run and Analyzer; gen's commands don't need it
+var chdirMu sync.Mutex
+
+// inDir calls fn with the working directory set to dir, restoring the original working directory when done
+func inDir(dir string, fn func() error) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	return fn()
+}
+
+// forPatterns calls fn in the current directory or, if patterns are given, in each package they match
+func forPatterns(c Config, patterns []string, fn func(c Config) error) error {
+	if len(patterns) == 0 {
+		if !c.json {
+			return c.timed(".", fn)
+		}
+
+		c.emit(event{Event: "package", Dir: "."})
+
+		err := c.timed(".", fn)
+		if err != nil {
+			c.emitLines("error", ".", err.Error())
+		}
+
+		return err
+	}
+
+	dirs, err := packages(c, patterns)
+	if err != nil {
+		return err
+	}
+
+	return forPackages(c, dirs, fn)
+}
+
+// forPackages calls fn (such as run) in each of dirs, reporting per-package results
+func forPackages(c Config, dirs []string, fn func(c Config) error) error {
+	var failed int
+
+	for _, dir := range dirs {
+		if err := c.ctx.Err(); err != nil {
+			return err
+		}
+
+		if c.json {
+			c.emit(event{Event: "package", Dir: dir})
+		}
+
+		err := inDir(dir, func() error {
+			return c.timed(dir, fn)
+		})
+
+		if c.json {
+			if err != nil {
+				failed++
+				c.emitLines("error", dir, err.Error())
+			}
+			continue
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(c.out, "FAIL\t%s\n%s\n", dir, err)
+			continue
+		}
+
+		fmt.Fprintf(c.out, "ok\t%s\n", dir)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("gen failed in %d of %d packages", failed, len(dirs))
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (keyed by slash-separated path) beneath root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var tree = map[string]string{
+	"a/a.go":          "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+	"a/b/b.go":        "package b\n\n// +gen slice:\"Any\"\ntype B int\n",
+	"c/c.go":          "package c\n\ntype C int\n",
+	"a/vendor/v/v.go": "package v\n\n// +gen slice:\"Where\"\ntype V int\n",
+	"a/testdata/t.go": "package t\n\n// +gen slice:\"Where\"\ntype T int\n",
+	"a/.hidden/h.go":  "package h\n\n// +gen slice:\"Where\"\ntype H int\n",
+	"d/d.go":          "package d\n\n// +generate\ntype D int\n",
+}
+
+func TestPackages(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_packages_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, tree)
+
+	dirs, err := packages(DefaultConfig, []string{root + "/..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{filepath.Join(root, "a"), filepath.Join(root, "a", "b")}
+
+	if len(dirs) != len(expected) {
+		t.Fatalf("packages should return %v, got %v", expected, dirs)
+	}
+
+	for i := range expected {
+		if dirs[i] != expected[i] {
+			t.Errorf("dirs[%d] should be %s, got %s", i, expected[i], dirs[i])
+		}
+	}
+
+	// relative patterns, from the current directory
+	err = inDir(root, func() error {
+		dirs, err := packages(DefaultConfig, []string{"./..."})
+		if err != nil {
+			return err
+		}
+
+		if len(dirs) != 2 || dirs[0] != "a" || dirs[1] != filepath.Join("a", "b") {
+			t.Errorf("packages should return a and a/b, got %v", dirs)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a plain directory is returned whether or not it has directives
+	dirs, err = packages(DefaultConfig, []string{filepath.Join(root, "c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dirs) != 1 {
+		t.Errorf("packages should return 1 directory, got %v", dirs)
+	}
+
+	if _, err := packages(DefaultConfig, []string{filepath.Join(root, "nope")}); err == nil {
+		t.Error("packages with a missing directory should be an error")
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_packages_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, tree)
+
+	tests := map[string]bool{
+		"./...":           true,
+		"./nope":          true, // missing, for packages to report
+		root:              true,
+		"a":               true,
+		"a/b":             true,
+		"a/...":           true,
+		"c/c.go":          false,
+		"nope":            false,
+		"example.com/foo": false,
+	}
+
+	err = inDir(root, func() error {
+		for arg, want := range tests {
+			if got := isPattern(arg); got != want {
+				t.Errorf("isPattern(%q) should be %v, got %v", arg, want, got)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForPackages(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_packages_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, tree)
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+
+	dirs, err := packages(DefaultConfig, []string{root + "/..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forPackages(c, dirs, run); err != nil {
+		t.Fatalf("%s\n%s", err, b.String())
+	}
+
+	for _, f := range []string{"a/a_slice.go", "a/b/b_slice.go"} {
+		if _, err := os.Stat(filepath.Join(root, f)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// one line per package
+	if lines := bytes.Count(b.Bytes(), []byte("\n")); lines != 2 {
+		t.Errorf("forPackages should output 2 lines, got %v:\n%s", lines, b.String())
+	}
+
+	// a package without directives is a failure, but others still run
+	b.Reset()
+	if err := forPackages(c, []string{filepath.Join(root, "c"), filepath.Join(root, "a")}, run); err == nil {
+		t.Error("forPackages should fail on a package without directives")
+	}
+
+	if !bytes.Contains(b.Bytes(), []byte("ok\t"+filepath.Join(root, "a"))) {
+		t.Errorf("forPackages should report success for package a, got:\n%s", b.String())
+	}
+}
+
+func TestPackagesOrphans(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_packages_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a/a.go": "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+
+	err = inDir(root, func() error {
+		dirs, err := packages(DefaultConfig, []string{"./..."})
+		if err != nil {
+			return err
+		}
+
+		if err := forPackages(c, dirs, run); err != nil {
+			return err
+		}
+
+		// remove the only tag, leaving a/a_slice.go orphaned
+		writeTree(t, root, map[string]string{
+			"a/a.go": "package a\n\ntype A int\n",
+		})
+
+		dirs, err = packages(DefaultConfig, []string{"./..."})
+		if err != nil {
+			return err
+		}
+
+		if len(dirs) != 1 || dirs[0] != "a" {
+			t.Fatalf("packages should return a, holding an orphan, got %v", dirs)
+		}
+
+		b.Reset()
+		if err := forPackages(c, dirs, check); err == nil {
+			t.Error("check ./... should fail on the orphaned a/a_slice.go")
+		}
+
+		if !strings.Contains(b.String(), "--- a/a_slice.go") {
+			t.Errorf("check ./... should print a deletion diff for a_slice.go, got:\n%s", b.String())
+		}
+
+		c.prune = true
+		c.yes = true
+		if err := forPackages(c, dirs, run); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(filepath.Join("a", "a_slice.go")); !os.IsNotExist(err) {
+			t.Errorf("run -prune ./... should remove a/a_slice.go, got %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("%s\n%s", err, b.String())
+	}
+}
@@ -0,0 +1,23 @@
+package gen
+
+import (
+	"sort"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+type pkg struct {
+	Name    string
+	Imports typewriter.ImportSpecSet
+	// Versions are pinned versions of imports, keyed by path; optional
+	Versions map[string]string
+}
+
+// SortedImports returns Imports ordered by path, so that generated source is deterministic
+func (p pkg) SortedImports() []typewriter.ImportSpec {
+	imps := p.Imports.ToSlice()
+	sort.Slice(imps, func(i, j int) bool {
+		return imps[i].Path < imps[j].Path
+	})
+	return imps
+}
@@ -0,0 +1,216 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultPollInterval is used when watch falls back to polling, not having been asked to
+const defaultPollInterval = 1 * time.Second
+
+// pollWatcher is a watcher which scans its directories every interval, for file systems on which fsnotify delivers
+// no events, such as network file systems and some container mounts. Files are compared by size and modification
+// time and, where the latter is too recent to be trusted (file systems may record it coarsely), by checksum.
+type pollWatcher struct {
+	interval time.Duration
+	events   chan fsnotify.Event
+	errors   chan error
+	done     chan struct{}
+	close    sync.Once
+
+	mu sync.Mutex
+	// dirs holds the last scan of each directory, keyed by file name
+	dirs map[string]map[string]fileState
+}
+
+// fileState is a file as last scanned by pollWatcher
+type fileState struct {
+	dir     bool
+	size    int64
+	modTime time.Time
+	sum     [sha256.Size]byte
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	p := &pollWatcher{
+		interval: interval,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+		dirs:     make(map[string]map[string]fileState),
+	}
+
+	go p.poll()
+
+	return p
+}
+
+// Add watches dir, as it is now; changes are reported from the next scan
+func (p *pollWatcher) Add(dir string) error {
+	files, err := scan(dir, nil)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.dirs[dir] = files
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *pollWatcher) Events() <-chan fsnotify.Event { return p.events }
+func (p *pollWatcher) Errors() <-chan error          { return p.errors }
+
+func (p *pollWatcher) Close() error {
+	p.close.Do(func() { close(p.done) })
+	return nil
+}
+
+func (p *pollWatcher) poll() {
+	defer close(p.events)
+	defer close(p.errors)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		p.mu.Lock()
+		dirs := make([]string, 0, len(p.dirs))
+		for dir := range p.dirs {
+			dirs = append(dirs, dir)
+		}
+		p.mu.Unlock()
+
+		for _, dir := range dirs {
+			if !p.scanDir(dir) {
+				return
+			}
+		}
+	}
+}
+
+// scanDir compares dir with its last scan, sending events for the differences. It reports false once closed.
+func (p *pollWatcher) scanDir(dir string) bool {
+	p.mu.Lock()
+	before := p.dirs[dir]
+	p.mu.Unlock()
+
+	after, err := scan(dir, before)
+	if os.IsNotExist(err) {
+		// as fsnotify, a removed directory is no longer watched
+		p.mu.Lock()
+		delete(p.dirs, dir)
+		p.mu.Unlock()
+		return true
+	}
+	if err != nil {
+		return p.send(nil, err)
+	}
+
+	p.mu.Lock()
+	p.dirs[dir] = after
+	p.mu.Unlock()
+
+	for name, f := range after {
+		event := fsnotify.Event{Name: filepath.Join(dir, name)}
+
+		if g, ok := before[name]; !ok {
+			event.Op = fsnotify.Create
+		} else if f.changed(g) {
+			event.Op = fsnotify.Write
+		} else {
+			continue
+		}
+
+		if !p.send(&event, nil) {
+			return false
+		}
+	}
+
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			if !p.send(&fsnotify.Event{Name: filepath.Join(dir, name), Op: fsnotify.Remove}, nil) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// send sends event or err, reporting false if closed meanwhile
+func (p *pollWatcher) send(event *fsnotify.Event, err error) bool {
+	if event != nil {
+		select {
+		case p.events <- *event:
+			return true
+		case <-p.done:
+			return false
+		}
+	}
+
+	select {
+	case p.errors <- err:
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// changed reports whether f differs from g, an earlier state of the same file
+func (f fileState) changed(g fileState) bool {
+	return f.dir != g.dir || f.size != g.size || !f.modTime.Equal(g.modTime) || f.sum != g.sum
+}
+
+// scan returns the state of the files of dir. Checksums of Go files are computed if modified lately, or if their size
+// or modification time has changed since before, and are otherwise carried over.
+func scan(dir string, before map[string]fileState) (map[string]fileState, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]fileState, len(infos))
+	recent := time.Now().Add(-2 * time.Second)
+
+	for _, fi := range infos {
+		// directories are watched separately, by Add; only their presence is of note here
+		if fi.IsDir() {
+			files[fi.Name()] = fileState{dir: true}
+			continue
+		}
+
+		f := fileState{
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+		}
+
+		// only Go files are of interest to watch, and worth reading
+		if strings.HasSuffix(fi.Name(), ".go") {
+			g, ok := before[fi.Name()]
+			if ok && g.size == f.size && g.modTime.Equal(f.modTime) && f.modTime.Before(recent) {
+				f.sum = g.sum
+			} else if src, err := ioutil.ReadFile(filepath.Join(dir, fi.Name())); err == nil {
+				f.sum = sha256.Sum256(src)
+			}
+		}
+
+		files[fi.Name()] = f
+	}
+
+	return files, nil
+}
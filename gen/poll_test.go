@@ -0,0 +1,139 @@
+package gen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestPollWatcher(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_poll_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	a := filepath.Join(root, "a.go")
+	if err := ioutil.WriteFile(a, []byte("package a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newPollWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	if err := w.Add(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Add(filepath.Join(root, "nonexistent")); err == nil {
+		t.Error("Add should fail for a nonexistent directory")
+	}
+
+	expect := func(name string, op fsnotify.Op) {
+		t.Helper()
+
+		select {
+		case event := <-w.Events():
+			if event.Name != name || event.Op != op {
+				t.Errorf("expected %v %s, got %v", op, name, event)
+			}
+		case err := <-w.Errors():
+			t.Fatal(err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %v %s, got nothing", op, name)
+		}
+	}
+
+	// the same size and modification time, as on a file system which records it coarsely
+	fi, err := os.Stat(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(a, []byte("package b\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(a, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	expect(a, fsnotify.Write)
+
+	b := filepath.Join(root, "b.go")
+	if err := ioutil.WriteFile(b, []byte("package a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	expect(b, fsnotify.Create)
+
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	expect(b, fsnotify.Remove)
+
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0777); err != nil {
+		t.Fatal(err)
+	}
+	expect(sub, fsnotify.Create)
+
+	// nothing further
+	select {
+	case event := <-w.Events():
+		t.Errorf("expected no events, got %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Close()
+
+	if _, ok := <-w.Events(); ok {
+		t.Error("Events should be closed by Close")
+	}
+}
+
+func TestWatchLoopPolling(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_poll_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	a := filepath.Join(root, "a.go")
+	if err := ioutil.WriteFile(a, []byte("package a\n\n// +gen slice:\"Where\"\ntype A int\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newPollWatcher(10 * time.Millisecond)
+	defer w.Close()
+
+	g := &fakeGen{runs: make(chan []string)}
+
+	c := DefaultConfig
+	c.out = ioutil.Discard
+	c.watchInterval = 10 * time.Millisecond
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- watchLoop(c, w, []string{root}, g.gen)
+	}()
+
+	// let the loop add root before changing a.go
+	time.Sleep(50 * time.Millisecond)
+
+	// replace a.go whole, lest a scan see it truncated, and then written, as two changes
+	b := filepath.Join(root, ".a.go")
+	if err := ioutil.WriteFile(b, []byte("package a\n\n// +gen slice:\"Where,Count\"\ntype A int\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(b, a); err != nil {
+		t.Fatal(err)
+	}
+	expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+	// the loop ends when the watcher is closed
+	w.Close()
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+}
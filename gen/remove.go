@@ -0,0 +1,136 @@
+package gen
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+// remove removes typewriter imports from the current package's _gen.go file, the inverse of add.
+//
+// If the remaining imports are the default ones, the file is removed altogether. A warning is printed
+// for any +gen tag which still refers to a removed typewriter, by the name it registers (see listTypewriters).
+func remove(c Config, args ...string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("please specify the import path of the typewriter you wish to remove")
+	}
+
+	if _, err := os.Stat(c.customName); err != nil {
+		return fmt.Errorf("%s not found; only typewriters added to it can be removed", c.customName)
+	}
+
+	imports, err := getTypewriterImports(c)
+
+	if err != nil {
+		return err
+	}
+
+	versions, err := getTypewriterVersions(c)
+
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		// a version is meaningless here, but allow it for symmetry with add
+		p := arg
+		if i := strings.LastIndex(arg, "@"); i >= 0 {
+			p = arg[:i]
+		}
+
+		found := false
+		for imp := range imports {
+			if imp.Path == p {
+				imports.Remove(imp)
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s is not imported by %s", p, c.customName)
+		}
+
+		delete(versions, p)
+	}
+
+	// while the typewriters are still imported, learn the names they register
+	tws, err := listTypewriters(c)
+	if err != nil {
+		// a typewriter which no longer builds may be why it is being removed
+		fmt.Fprintf(c.out, "warning: +gen tags referring to removed typewriters are not checked, as typewriters could not be listed: %v\n", err)
+	}
+
+	if imports.Equal(c.defaultImports()) {
+		if err := os.Remove(c.customName); err != nil {
+			return err
+		}
+	} else if err := writeCustomFile(c, imports, versions); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		p := strings.SplitN(arg, "@", 2)[0]
+
+		for _, tw := range tws {
+			if tw.Path != p {
+				continue
+			}
+
+			refs, err := tagReferences("./", "+gen", tw.Name)
+			if err != nil {
+				return err
+			}
+
+			for _, pos := range refs {
+				fmt.Fprintf(c.out, "warning: %s: +gen tag %q refers to the removed typewriter %s\n", pos, tw.Name, p)
+			}
+		}
+	}
+
+	return nil
+}
+
+// tagReferences returns the positions of directive comments in dir having a tag called name
+func tagReferences(dir, directive, name string) ([]token.Position, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, sourceFile, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []token.Position
+
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, g := range f.Comments {
+				for _, c := range g.List {
+					if !isDirective(c.Text, directive) {
+						continue
+					}
+
+					t := strings.TrimPrefix(strings.TrimLeft(c.Text, "/ "), directive)
+
+					for _, tag := range splitOutsideQuotes(t, ' ') {
+						// tags are of the form name or name:"values"
+						if strings.SplitN(tag, ":", 2)[0] == name {
+							refs = append(refs, fset.Position(c.Slash))
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// files are parsed in no particular order
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Filename != refs[j].Filename {
+			return refs[i].Filename < refs[j].Filename
+		}
+		return refs[i].Offset < refs[j].Offset
+	})
+
+	return refs, nil
+}
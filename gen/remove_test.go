@@ -0,0 +1,123 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestRemove(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_remove_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go": "package a\n\n// +gen slice:\"Where\" hashset\ntype A int\n",
+	})
+
+	// setwriter registers as hashset, which can't be guessed from its path
+	tool := newFakeModule()
+	tool.exec = `[{"name":"foo","path":"github.com/clipperhouse/foowriter"},{"name":"hashset","path":"github.com/clipperhouse/setwriter"},` +
+		`{"name":"slice","path":"github.com/clipperhouse/slice"},{"name":"stringer","path":"github.com/clipperhouse/stringer"}]` + "\n"
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.tool = tool
+	c.cacheDir = filepath.Join(root, "cache")
+
+	foo := typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/foowriter"}
+	set := typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/setwriter"}
+
+	err = inDir(root, func() error {
+		if err := remove(c); err == nil {
+			t.Error("remove with no arguments should be an error")
+		}
+
+		if err := remove(c, foo.Path); err == nil {
+			t.Error("remove without a custom file should be an error")
+		}
+
+		imps := c.defaultImports().Clone()
+		imps.Add(foo)
+		imps.Add(set)
+
+		if err := createCustomFile(c, imps, map[string]string{set.Path: "v1.0.0"}); err != nil {
+			return err
+		}
+
+		if err := remove(c, "github.com/clipperhouse/barwriter"); err == nil {
+			t.Error("removing a typewriter which is not imported should be an error")
+		}
+
+		if err := remove(c, set.Path); err != nil {
+			return err
+		}
+
+		after, err := getTypewriterImports(c)
+		if err != nil {
+			return err
+		}
+
+		if after.Contains(set) || !after.Contains(foo) {
+			t.Errorf("imports should include only %s, got %v", foo.Path, after)
+		}
+
+		versions, err := getTypewriterVersions(c)
+		if err != nil {
+			return err
+		}
+
+		if len(versions) != 0 {
+			t.Errorf("pinned version of %s should be removed, got %v", set.Path, versions)
+		}
+
+		// the type still has a hashset tag
+		if !strings.Contains(b.String(), `a.go:3:1: +gen tag "hashset" refers to the removed typewriter`) {
+			t.Errorf("remove should warn of the remaining hashset tag, got:\n%s", b.String())
+		}
+
+		b.Reset()
+
+		// back to the standard imports, so the file is no longer needed
+		if err := remove(c, foo.Path); err != nil {
+			return err
+		}
+
+		if _, err := os.Stat(c.customName); err == nil {
+			t.Errorf("%s should be removed when only standard imports remain", c.customName)
+		}
+
+		if b.Len() > 0 {
+			t.Errorf("remove should not warn when no tags refer to foo, got:\n%s", b.String())
+		}
+
+		// a typewriter which doesn't build can still be removed, though its tags can't be checked
+		if err := createCustomFile(c, imps, nil); err != nil {
+			return err
+		}
+
+		tool.fail = map[string]string{"list": "cannot find module providing package"}
+
+		if err := remove(c, set.Path); err != nil {
+			return err
+		}
+
+		if !strings.Contains(b.String(), "typewriters could not be listed") {
+			t.Errorf("remove should warn that tags are not checked, got:\n%s", b.String())
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,392 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/template"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func run(c Config) error {
+	files, types, err := generate(c)
+
+	if err != nil {
+		// the last directive may have been removed, leaving only orphans
+		if !hasDirective(c, "./", "+gen") {
+			if names, _ := orphans(c, nil); len(names) > 0 {
+				return prune(c, nil)
+			}
+		}
+		return err
+	}
+
+	c.emitTypes(types)
+
+	if c.dryRun {
+		for _, f := range files {
+			if c.json {
+				c.emit(event{Event: "generate", File: f.Name, Source: string(f.Src)})
+				continue
+			}
+			fmt.Fprintf(c.out, "==> %s <==\n", f.Name)
+			c.out.Write(f.Src)
+			fmt.Fprintln(c.out)
+		}
+		return prune(c, files)
+	}
+
+	for _, f := range files {
+		if err := ioutil.WriteFile(f.Name, f.Src, 0666); err != nil {
+			return err
+		}
+		c.wrote(f.Name)
+
+		if c.json {
+			c.emit(event{Event: "write", File: f.Name})
+		}
+	}
+
+	return prune(c, files)
+}
+
+// generate runs the typewriters for the current package, returning the resulting files without writing them,
+// and the types marked with +gen. Invalid tags are an error, or with -f a warning; see reportProblems.
+func generate(c Config) ([]file, []typeInfo, error) {
+	files, types, problems, err := generateUnchecked(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := reportProblems(c, problems); err != nil {
+		return nil, nil, err
+	}
+
+	return files, types, nil
+}
+
+// generateUnchecked is generate, returning the problems found in +gen tags rather than reporting them. Files are
+// generated for valid tags only.
+func generateUnchecked(c Config) ([]file, []typeInfo, []problem, error) {
+	var result runOutput
+	if err := executeJSON(runStandard, c, runImports, runTmpl, &result); err != nil {
+		return nil, nil, nil, err
+	}
+
+	files, err := format(c, result.Generated)
+
+	return files, result.Types, result.Problems, err
+}
+
+func runStandard(c Config) (err error) {
+	app, err := c.Config.NewApp("+gen")
+
+	if err != nil {
+		return err
+	}
+
+	if len(app.Packages) == 0 {
+		return fmt.Errorf("No packages were found. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	found := false
+
+	for _, p := range app.Packages {
+		found = found || len(p.Types) > 0
+	}
+
+	if !found {
+		return fmt.Errorf("No types marked with +gen were found. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	if len(app.TypeWriters) == 0 {
+		return fmt.Errorf("No typewriters were imported. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	// describe types before validate removes any invalid tags
+	types := typesOf(app)
+	problems := validate(app)
+
+	gens, err := generateAll(app)
+
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(c.out).Encode(runOutput{types, problems, gens})
+}
+
+// passThrough writes output from the run or list pipeline (such as from typewriters) to c.out; as output events if -json
+func (c Config) passThrough(output []byte) {
+	if !c.json {
+		c.out.Write(output)
+		return
+	}
+
+	c.emitLines("output", "", string(output))
+}
+
+// runImports are the imports of runTmpl
+var runImports = typewriter.NewImportSpecSet(
+	typewriter.ImportSpec{Path: "bytes"},
+	typewriter.ImportSpec{Path: "encoding/json"},
+	typewriter.ImportSpec{Path: "fmt"},
+	typewriter.ImportSpec{Path: "io/ioutil"},
+	typewriter.ImportSpec{Path: "os"},
+	typewriter.ImportSpec{Path: "path/filepath"},
+	typewriter.ImportSpec{Path: "regexp"},
+	typewriter.ImportSpec{Path: "github.com/clipperhouse/typewriter"},
+)
+
+var runTmpl = template.Must(template.New("run").Parse(`
+
+var exitStatusMsg = regexp.MustCompile("^exit status \\d+$")
+
+func main() {
+	var err error
+
+	defer func() {
+		if err != nil {
+			if !exitStatusMsg.MatchString(err.Error()) {
+				os.Stderr.WriteString(err.Error() + "\n")
+			}
+			os.Exit(1)
+		}
+	}()
+
+	err = run()
+}
+
+// keep in sync with matchName in config.go
+func matchName(name string, include, exclude []string) bool {
+	matched := len(include) == 0
+
+	for _, pattern := range include {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		return false
+	}
+
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// keep in sync with generated in generate.go
+type generated struct {
+	Type, TypeWriter string
+	Src              []byte
+}
+
+// keep in sync with problem in validate.go
+type problem struct {
+	Type, Tag, Value, Message string
+}
+
+// keep in sync with runOutput in generate.go
+type runOutput struct {
+	Types     []typeInfo
+	Problems  []problem
+	Generated []generated
+}
+
+// keep in sync with typeInfo in events.go; field names are matched case-insensitively
+type typeInfo struct {
+	Package                      string
+	Name                         string
+	Pointer                      bool
+	Comparable, Numeric, Ordered bool
+	Tags                         []tagInfo
+}
+
+type tagInfo struct {
+	Name    string
+	Negated bool
+	Values  []tagValueInfo
+}
+
+type tagValueInfo struct {
+	Name           string
+	TypeParameters []string
+}
+
+// keep in sync with typesOf in generate.go
+func typesOf(app *typewriter.App) []typeInfo {
+	var types []typeInfo
+
+	for _, p := range app.Packages {
+		for _, t := range p.Types {
+			info := typeInfo{
+				Package:    p.Name(),
+				Name:       t.Name,
+				Pointer:    bool(t.Pointer),
+				Comparable: typewriter.Constraint{Comparable: true}.TryType(t) == nil,
+				Numeric:    typewriter.Constraint{Numeric: true}.TryType(t) == nil,
+				Ordered:    typewriter.Constraint{Ordered: true}.TryType(t) == nil,
+			}
+
+			for _, tag := range t.Tags {
+				ti := tagInfo{
+					Name:    tag.Name,
+					Negated: tag.Negated,
+				}
+
+				for _, v := range tag.Values {
+					vi := tagValueInfo{Name: v.Name}
+					for _, tp := range v.TypeParameters {
+						vi.TypeParameters = append(vi.TypeParameters, tp.String())
+					}
+					ti.Values = append(ti.Values, vi)
+				}
+
+				info.Tags = append(info.Tags, ti)
+			}
+
+			types = append(types, info)
+		}
+	}
+
+	return types
+}
+
+type templater interface {
+	Templates() typewriter.TemplateSlice
+}
+
+// keep in sync with validate in validate.go
+func validate(app *typewriter.App) []problem {
+	tws := make(map[string]typewriter.Interface)
+	for _, tw := range app.TypeWriters {
+		tws[tw.Name()] = tw
+	}
+
+	var problems []problem
+
+	for _, p := range app.Packages {
+		for i := range p.Types {
+			t := &p.Types[i]
+
+			var tags typewriter.TagSlice
+
+			for _, tag := range t.Tags {
+				tw, ok := tws[tag.Name]
+				if !ok {
+					problems = append(problems, problem{t.Name, tag.Name, "", fmt.Sprintf("unknown typewriter %q", tag.Name)})
+					continue
+				}
+
+				var values []typewriter.TagValue
+
+				for _, v := range tag.Values {
+					if err := tryValue(tw, *t, tag, v); err != nil {
+						problems = append(problems, problem{t.Name, tag.Name, v.Name, fmt.Sprintf("%s: %s", tag.Name, err)})
+						continue
+					}
+					values = append(values, v)
+				}
+
+				tag.Values = values
+				tags = append(tags, tag)
+			}
+
+			t.Tags = tags
+		}
+	}
+
+	return problems
+}
+
+// keep in sync with tryValue in validate.go
+func tryValue(tw typewriter.Interface, t typewriter.Type, tag typewriter.Tag, v typewriter.TagValue) error {
+	if tmpl, ok := tw.(templater); ok {
+		_, err := tmpl.Templates().ByTagValue(t, v)
+		return err
+	}
+
+	tag.Values = []typewriter.TagValue{v}
+	t.Tags = typewriter.TagSlice{tag}
+
+	return tw.Write(ioutil.Discard, t)
+}
+
+func run() error {
+	config := &typewriter.Config{
+		IgnoreTypeCheckErrors: {{ .IgnoreTypeCheckErrors }},
+		Filter: func(fi os.FileInfo) bool {
+			return matchName(fi.Name(), {{ printf "%#v" .Files.Include }}, {{ printf "%#v" .Files.Exclude }})
+		},
+	}
+	app, err := config.NewApp("+gen")
+
+	if err != nil {
+		return err
+	}
+
+	if len(app.Packages) == 0 {
+		return fmt.Errorf("No packages were found. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	found := false
+
+	for _, p := range app.Packages {
+		found = found || len(p.Types) > 0
+	}
+
+	if !found {
+		return fmt.Errorf("No types marked with +gen were found. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	if len(app.TypeWriters) == 0 {
+		return fmt.Errorf("No typewriters were imported. See http://clipperhouse.github.io/gen to get started, or type %s help.", os.Args[0])
+	}
+
+	types := typesOf(app)
+	problems := validate(app)
+
+	var gens []generated
+
+	for _, p := range app.Packages {
+		for _, t := range p.Types {
+			for _, tw := range app.TypeWriters {
+				var b bytes.Buffer
+
+				fmt.Fprintf(&b, "// Generated by: gen\n// TypeWriter: %s\n// Directive: %s on %s\n\n", tw.Name(), app.Directive, t.String())
+				fmt.Fprintf(&b, "package %s\n\n", p.Name())
+
+				if imps := tw.Imports(t); len(imps) > 0 {
+					b.WriteString("import (\n")
+					for _, imp := range imps {
+						fmt.Fprintf(&b, "\t%s %q\n", imp.Name, imp.Path)
+					}
+					b.WriteString(")\n")
+				}
+
+				n := b.Len()
+
+				if err := tw.Write(&b, t); err != nil {
+					return err
+				}
+
+				if b.Len() == n {
+					continue
+				}
+
+				gens = append(gens, generated{t.Name, tw.Name(), b.Bytes()})
+			}
+		}
+	}
+
+	return json.NewEncoder(os.Stdout).Encode(runOutput{types, problems, gens})
+}
+`))
@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// +gen slice:"Where"
+type dummy int
+
+func TestRun(t *testing.T) {
+	// use custom name so test won't interfere with a real _gen.go
+	c := DefaultConfig
+	c.customName = "_gen_run_test.go"
+
+	sliceName := "dummy_slice_test.go"
+	fooName := "dummy_foo_test.go"
+
+	// standard run
+	if err := run(c); err != nil {
+		t.Fatal(err)
+	}
+
+	// gen file should exist
+	if _, err := os.Stat(sliceName); err != nil {
+		t.Error(err)
+	}
+
+	// foo file should not exist, not a standard typewriter
+	if _, err := os.Stat(fooName); err == nil {
+		t.Errorf("%s should not have been generated", fooName)
+	}
+
+	// remove just-gen'd file
+	if err := os.Remove(sliceName); err != nil {
+		t.Fatal(err)
+	}
+
+	// create a custom typewriter import file
+	imports := typewriter.NewImportSpecSet(
+		typewriter.ImportSpec{Name: "_", Path: "github.com/clipperhouse/foowriter"},
+	)
+
+	if err := createCustomFile(c, imports, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// custom run: slice is not imported, so the slice tag on dummy is an unknown typewriter
+	if err := run(c); err == nil || !strings.Contains(err.Error(), `unknown typewriter "slice"`) {
+		t.Errorf("run should report the slice tag as an unknown typewriter, got %v", err)
+	}
+
+	// with -f, the tag is ignored, with a warning
+	conf := *c.Config
+	conf.IgnoreTypeCheckErrors = true
+	c.Config = &conf
+	c.out = ioutil.Discard
+
+	if err := run(c); err != nil {
+		t.Error(err)
+	}
+
+	// clean up custom file, no longer needed
+	if err := os.Remove(c.customName); err != nil {
+		t.Fatal(err)
+	}
+
+	// foo file should exist
+	if _, err := os.Stat(fooName); err != nil {
+		t.Error(err)
+	}
+
+	// clean up foo file
+	if err := os.Remove(fooName); err != nil {
+		t.Fatal(err)
+	}
+
+	// gen file should not exist, because slice was not included in the custom file
+	if _, err := os.Stat(sliceName); err == nil {
+		t.Errorf("%s should not have been generated", sliceName)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_run_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go":      "package a\n\n// +gen slice:\"Where\"\ntype A int\n",
+		"a_test.go": "package a\n\n// +gen slice:\"Any\"\ntype B int\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.dryRun = true
+
+	err = inDir(root, func() error {
+		return run(c)
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []string{"a_slice.go", "b_slice_test.go"} {
+		if !strings.Contains(b.String(), "==> "+f+" <==\n// Generated by:") {
+			t.Errorf("dry run should output %s, got:\n%s", f, b.String())
+		}
+
+		if _, err := os.Stat(filepath.Join(root, f)); err == nil {
+			t.Errorf("dry run should not write %s", f)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package gen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// temps tracks temp directories, so that they can be removed if gen is interrupted
+var temps = struct {
+	sync.Mutex
+	dirs map[string]struct{}
+}{
+	dirs: make(map[string]struct{}),
+}
+
+// getTempDir sets up a temp directory in the system location, outside of the user's package
+// make sure to defer removeTempDir() in caller
+func getTempDir() (string, error) {
+	caller := filepath.Base(os.Args[0])
+
+	dir, err := ioutil.TempDir("", caller)
+	if err != nil {
+		return "", err
+	}
+
+	temps.Lock()
+	temps.dirs[dir] = s
+	temps.Unlock()
+
+	return dir, nil
+}
+
+func removeTempDir(dir string) error {
+	temps.Lock()
+	delete(temps.dirs, dir)
+	temps.Unlock()
+
+	return os.RemoveAll(dir)
+}
+
+// isTemp reports whether the named file (an absolute path) is within an outstanding temp directory
+func isTemp(name string) bool {
+	temps.Lock()
+	defer temps.Unlock()
+
+	for dir := range temps.dirs {
+		if strings.HasPrefix(name, abs(dir)+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeTempDirs removes all outstanding temp directories
+func removeTempDirs() {
+	temps.Lock()
+	defer temps.Unlock()
+
+	for dir := range temps.dirs {
+		os.RemoveAll(dir)
+		delete(temps.dirs, dir)
+	}
+}
+
+// interrupt holds a func to call on SIGINT or SIGTERM in place of exiting, such as to let watch finish gracefully;
+// see cancelOnSignal
+var interrupt = struct {
+	sync.Mutex
+	cancel func()
+}{}
+
+var cleanupOnce sync.Once
+
+// cleanupOnSignal removes temp directories and exits when gen receives SIGINT or SIGTERM, unless cancelOnSignal is in
+// effect, in which case only a second signal exits. Calls after the first have no effect.
+func cleanupOnSignal() {
+	cleanupOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+		go func() {
+			for range ch {
+				interrupt.Lock()
+				cancel := interrupt.cancel
+				interrupt.cancel = nil
+				interrupt.Unlock()
+
+				if cancel != nil {
+					cancel()
+					continue
+				}
+
+				removeTempDirs()
+				os.Exit(1)
+			}
+		}()
+	})
+}
+
+// cancelOnSignal arranges for SIGINT or SIGTERM to call cancel, rather than exit, until restore is called.
+// It has no effect unless cleanupOnSignal has been called, as by Main.
+func cancelOnSignal(cancel func()) (restore func()) {
+	interrupt.Lock()
+	interrupt.cancel = cancel
+	interrupt.Unlock()
+
+	return func() {
+		interrupt.Lock()
+		interrupt.cancel = nil
+		interrupt.Unlock()
+	}
+}
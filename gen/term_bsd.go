@@ -0,0 +1,8 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package gen
+
+import "golang.org/x/sys/unix"
+
+const ioctlReadTermios = unix.TIOCGETA
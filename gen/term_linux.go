@@ -0,0 +1,5 @@
+package gen
+
+import "golang.org/x/sys/unix"
+
+const ioctlReadTermios = unix.TCGETS
@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package gen
+
+// isTerminalFd is false where terminals can't be detected, so that gen doesn't prompt
+func isTerminalFd(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,150 @@
+package gen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by gen.toml: top-level tables, and keys with
+// string, boolean, integer or string array values. Arrays may span lines.
+//
+// The result is suitable for conversion (via encoding/json) to a struct.
+func parseTOML(src []byte) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	table := result
+
+	lines := strings.Split(string(src), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		n := i + 1 // line number, for errors
+		line := strings.TrimSpace(stripComment(lines[i]))
+
+		if len(line) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: expected ] at end of table name", n)
+			}
+
+			name := strings.TrimSpace(line[1 : len(line)-1])
+
+			if _, exists := result[name]; exists {
+				return nil, fmt.Errorf("line %d: duplicate table %q", n, name)
+			}
+
+			table = make(map[string]interface{})
+			result[name] = table
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+
+		key := strings.Trim(strings.TrimSpace(line[:eq]), `"`)
+		value := strings.TrimSpace(line[eq+1:])
+
+		// an array may span lines
+		if strings.HasPrefix(value, "[") {
+			for !strings.HasSuffix(value, "]") && i+1 < len(lines) {
+				i++
+				value += " " + strings.TrimSpace(stripComment(lines[i]))
+			}
+		}
+
+		if _, exists := table[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", n, key)
+		}
+
+		v, err := parseTOMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		table[key] = v
+	}
+
+	return result, nil
+}
+
+func parseTOMLValue(value string) (interface{}, error) {
+	switch {
+	case value == "true":
+		return true, nil
+	case value == "false":
+		return false, nil
+	case strings.HasPrefix(value, `"`):
+		return strconv.Unquote(value)
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("unterminated string %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("unterminated array %s", value)
+		}
+
+		var result []interface{}
+
+		for _, el := range splitOutsideQuotes(value[1:len(value)-1], ',') {
+			el = strings.TrimSpace(el)
+			if len(el) == 0 {
+				// trailing comma
+				continue
+			}
+
+			v, err := parseTOMLValue(el)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, v)
+		}
+
+		return result, nil
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i, nil
+	}
+
+	return nil, fmt.Errorf("unsupported value %s", value)
+}
+
+// stripComment removes a # comment from line, ignoring any # within quotes
+func stripComment(line string) string {
+	if parts := splitOutsideQuotes(line, '#'); len(parts) > 1 {
+		return parts[0]
+	}
+	return line
+}
+
+// splitOutsideQuotes splits s on sep, ignoring any sep within quotes
+func splitOutsideQuotes(s string, sep byte) []string {
+	var result []string
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == '"' && c == '\\':
+			i++ // skip escaped character
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == sep:
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(result, s[start:])
+}
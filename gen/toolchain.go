@@ -0,0 +1,107 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// toolchain runs the go tool, and the runners built with it (see executeCustom). Commands use the toolchain on
+// Config rather than running the go tool directly, so that it can be replaced, such as by a fake in tests.
+type toolchain interface {
+	// Go runs the go tool with args in dir, or in the working directory if dir is empty. Env is added to the environment.
+	Go(dir string, env []string, stdout, stderr io.Writer, args ...string) error
+	// Exec runs a compiled runner, in the working directory
+	Exec(exe string, stdout, stderr io.Writer) error
+}
+
+// goToolchain is the real toolchain, running the go tool found at path (or, if not a path, on PATH)
+type goToolchain struct {
+	path string
+}
+
+func (t goToolchain) Go(dir string, env []string, stdout, stderr io.Writer, args ...string) error {
+	cmd := exec.Command(t.path, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd.Run()
+}
+
+func (t goToolchain) Exec(exe string, stdout, stderr io.Writer) error {
+	cmd := exec.Command(exe)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return cmd.Run()
+}
+
+// toolFlag sets a goToolchain, for the -go flag
+type toolFlag struct {
+	tool *toolchain
+}
+
+func (f toolFlag) String() string {
+	if f.tool != nil {
+		if t, ok := (*f.tool).(goToolchain); ok {
+			return t.path
+		}
+	}
+	return ""
+}
+
+func (f toolFlag) Set(path string) error {
+	*f.tool = goToolchain{path}
+	return nil
+}
+
+// goOutput runs the go tool, returning its standard output; standard error is included in any error
+func (c Config) goOutput(args ...string) ([]byte, error) {
+	return c.goOutputDir("", args...)
+}
+
+// goOutputDir is goOutput, run in dir
+func (c Config) goOutputDir(dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	if err := c.tool.Go(dir, nil, &stdout, &stderr, args...); err != nil {
+		return nil, &toolError{args, stderr.String(), err}
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// goRun runs the go tool in dir, with standard output to c.out. Standard error is captured and included in
+// any error (see toolError), or else passed on to c.out. Env, if any, is added to the environment.
+func (c Config) goRun(dir string, env []string, args ...string) error {
+	var stderr bytes.Buffer
+
+	if err := c.tool.Go(dir, env, c.out, &stderr, args...); err != nil {
+		return &toolError{args, stderr.String(), err}
+	}
+
+	c.out.Write(stderr.Bytes())
+	return nil
+}
+
+// toolError is a failed run of the go tool
+type toolError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *toolError) Error() string {
+	return fmt.Sprintf("go %s: %v\n%s", e.Args[0], e.Err, e.Stderr)
+}
+
+func (e *toolError) Unwrap() error {
+	return e.Err
+}
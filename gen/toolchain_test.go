@@ -0,0 +1,105 @@
+package gen
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeToolchain is an in-memory toolchain, recording the commands it is asked to run
+type fakeToolchain struct {
+	// output is written to stdout by go commands, keyed by the (longest matching) prefix of their args joined with spaces
+	output map[string]string
+	// fail makes a go subcommand fail, with the value written to stderr
+	fail map[string]string
+	// exec is written to stdout by runners
+	exec string
+
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeToolchain) Go(dir string, env []string, stdout, stderr io.Writer, args ...string) error {
+	call := strings.Join(args, " ")
+
+	f.mu.Lock()
+	f.calls = append(f.calls, "go "+call)
+	f.mu.Unlock()
+
+	if msg, ok := f.fail[args[0]]; ok {
+		io.WriteString(stderr, msg)
+		return errors.New("exit status 1")
+	}
+
+	// runner must exist, to be cached & executed
+	if args[0] == "build" {
+		for i, arg := range args[:len(args)-1] {
+			if arg == "-o" {
+				if err := ioutil.WriteFile(args[i+1], []byte("fake runner"), 0777); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var match string
+	for prefix := range f.output {
+		if strings.HasPrefix(call, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+
+	if len(match) > 0 {
+		io.WriteString(stdout, f.output[match])
+	}
+
+	return nil
+}
+
+func (f *fakeToolchain) Exec(exe string, stdout, stderr io.Writer) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, "exec")
+	f.mu.Unlock()
+
+	io.WriteString(stdout, f.exec)
+	return nil
+}
+
+// called reports whether a command containing s was run
+func (f *fakeToolchain) called(s string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, call := range f.calls {
+		if strings.Contains(call, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// newFakeModule returns a fake toolchain in module mode, resolving any typewriter to v1.2.3
+func newFakeModule() *fakeToolchain {
+	return &fakeToolchain{
+		output: map[string]string{
+			"env GOMOD": "/src/example.com/a/go.mod\n",
+			"version":   "go version fake\n",
+			"list -f":   "v1.2.3\n",
+		},
+	}
+}
+
+func TestToolFlag(t *testing.T) {
+	c := DefaultConfig
+
+	if _, _, err := parseArgs(&c, []string{"gen", "get", "-go", "/opt/go/bin/go", "-u"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tool, ok := c.tool.(goToolchain); !ok || tool.path != "/opt/go/bin/go" {
+		t.Errorf("-go should set the go tool, got %v", c.tool)
+	}
+}
@@ -0,0 +1,216 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+// problem is a +gen tag, or tag value, which no typewriter can satisfy.
+// It is passed as JSON from the run pipeline back to gen; keep in sync with runTmpl.
+type problem struct {
+	Type, Tag, Value, Message string
+}
+
+// validate cross-references the tags of each type in app against its typewriters, returning problems: unknown
+// typewriters, unknown values and values whose type constraints are not met. Offending tags and values are
+// removed from app, so that the rest may be generated regardless. Keep in sync with runTmpl.
+func validate(app *typewriter.App) []problem {
+	tws := make(map[string]typewriter.Interface)
+	for _, tw := range app.TypeWriters {
+		tws[tw.Name()] = tw
+	}
+
+	var problems []problem
+
+	for _, p := range app.Packages {
+		for i := range p.Types {
+			t := &p.Types[i]
+
+			var tags typewriter.TagSlice
+
+			for _, tag := range t.Tags {
+				tw, ok := tws[tag.Name]
+				if !ok {
+					problems = append(problems, problem{t.Name, tag.Name, "", fmt.Sprintf("unknown typewriter %q", tag.Name)})
+					continue
+				}
+
+				var values []typewriter.TagValue
+
+				for _, v := range tag.Values {
+					if err := tryValue(tw, *t, tag, v); err != nil {
+						problems = append(problems, problem{t.Name, tag.Name, v.Name, fmt.Sprintf("%s: %s", tag.Name, err)})
+						continue
+					}
+					values = append(values, v)
+				}
+
+				tag.Values = values
+				tags = append(tags, tag)
+			}
+
+			t.Tags = tags
+		}
+	}
+
+	return problems
+}
+
+// templater may be implemented by typewriters, to make their templates (the tag values they accept) known
+type templater interface {
+	Templates() typewriter.TemplateSlice
+}
+
+// tryValue checks that tw accepts the value v of tag on t: against its templates if it makes them known
+// (see templater), otherwise by writing t with v alone. Keep in sync with runTmpl.
+func tryValue(tw typewriter.Interface, t typewriter.Type, tag typewriter.Tag, v typewriter.TagValue) error {
+	if tmpl, ok := tw.(templater); ok {
+		_, err := tmpl.Templates().ByTagValue(t, v)
+		return err
+	}
+
+	tag.Values = []typewriter.TagValue{v}
+	t.Tags = typewriter.TagSlice{tag}
+
+	return tw.Write(ioutil.Discard, t)
+}
+
+// reportProblems reports problems, positioned as for problemLines. They are an error unless type check errors are
+// ignored (-f), in which case they are warnings.
+func reportProblems(c Config, problems []problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	lines, err := problemLines(problems)
+	if err != nil {
+		return err
+	}
+
+	msg := strings.Join(lines, "\n")
+
+	if !c.IgnoreTypeCheckErrors {
+		return errors.New(msg)
+	}
+
+	for _, line := range lines {
+		c.warn(line)
+	}
+
+	if c.json {
+		c.emitLines("warning", "", msg)
+		return nil
+	}
+
+	for _, line := range lines {
+		fmt.Fprintf(c.out, "warning: %s\n", line)
+	}
+
+	return nil
+}
+
+// problemLines describes problems, positioned at their +gen directives in the current directory as file:line:col
+func problemLines(problems []problem) ([]string, error) {
+	fset := token.NewFileSet()
+	comments, err := directiveComments(fset, ".", "+gen")
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+
+	for _, p := range problems {
+		comment, ok := comments[p.Type]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s: %s", p.Type, p.Message))
+			continue
+		}
+
+		pos := fset.Position(comment.Slash + token.Pos(tagOffset(comment.Text, p.Tag, p.Value)))
+		lines = append(lines, fmt.Sprintf("%s: %s", pos, p.Message))
+	}
+
+	return lines, nil
+}
+
+// directiveComments returns the comments bearing directive in the Go files of dir, keyed by the name of the type they mark
+func directiveComments(fset *token.FileSet, dir, directive string) (map[string]*ast.Comment, error) {
+	pkgs, err := parser.ParseDir(fset, dir, sourceFile, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make(map[string]*ast.Comment)
+
+	for _, p := range pkgs {
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				g, ok := decl.(*ast.GenDecl)
+				if !ok || g.Tok != token.TYPE {
+					continue
+				}
+
+				for _, s := range g.Specs {
+					t := s.(*ast.TypeSpec)
+
+					doc := t.Doc
+					if g.Lparen == 0 {
+						doc = g.Doc
+					}
+
+					if c := directiveComment(doc, directive); c != nil {
+						comments[t.Name.Name] = c
+					}
+				}
+			}
+		}
+	}
+
+	return comments, nil
+}
+
+// directiveComment returns the first comment in doc beginning with directive, as typewriter does
+func directiveComment(doc *ast.CommentGroup, directive string) *ast.Comment {
+	if doc == nil {
+		return nil
+	}
+
+	for _, c := range doc.List {
+		if isDirective(c.Text, directive) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// tagOffset returns the offset in the directive comment text of the value of tag or, without a value, of the tag
+// itself; 0 if not found
+func tagOffset(text, tag, value string) int {
+	loc := regexp.MustCompile(`\s` + regexp.QuoteMeta(tag) + `(:|\s|$)`).FindStringIndex(text)
+	if loc == nil {
+		return 0
+	}
+
+	offset := loc[0] + 1
+
+	if len(value) == 0 {
+		return offset
+	}
+
+	// the value is within the quoted values following the tag
+	loc = regexp.MustCompile(`["\s,-]` + regexp.QuoteMeta(value) + `["\s,\[]`).FindStringIndex(text[offset:])
+	if loc == nil {
+		return offset
+	}
+
+	return offset + loc[0] + 1
+}
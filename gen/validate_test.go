@@ -0,0 +1,79 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/clipperhouse/typewriter"
+)
+
+func TestValidate(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_validate_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	writeTree(t, root, map[string]string{
+		"a.go": "package a\n\n// +gen slice:\"Where,Wehre\"\ntype A int\n\n// +gen slcie:\"Where\" slice:\"Sort,Any\"\ntype B struct{ f []int }\n",
+	})
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.Config = &typewriter.Config{}
+
+	expected := []string{
+		`a.go:3:22: slice: Wehre is unknown`,
+		`a.go:6:9: unknown typewriter "slcie"`,
+		`a.go:6:30: slice: cannot apply Sort to B: B must be ordered`,
+	}
+
+	err = inDir(root, func() error {
+		err := run(c)
+		if err == nil {
+			t.Fatal("run with invalid tags should be an error")
+		}
+
+		for _, s := range expected {
+			if !strings.Contains(err.Error(), s) {
+				t.Errorf("run should report %q, got:\n%s", s, err)
+			}
+		}
+
+		if _, err := os.Stat("a_slice.go"); err == nil {
+			t.Error("run with invalid tags should not write files")
+		}
+
+		// with -f, problems are warnings and valid tags are generated
+		c.IgnoreTypeCheckErrors = true
+
+		if err := run(c); err != nil {
+			return err
+		}
+
+		for _, s := range expected {
+			if !strings.Contains(b.String(), "warning: "+s) {
+				t.Errorf("run -f should warn %q, got:\n%s", s, b.String())
+			}
+		}
+
+		src, err := ioutil.ReadFile("b_slice.go")
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Contains(src, []byte(") Any(")) || bytes.Contains(src, []byte(") Sort(")) {
+			t.Errorf("run -f should generate Any but not Sort for B, got:\n%s", src)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,567 @@
+package gen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+// Analyzer reports problems with +gen directives and generated files: malformed directives, directives on
+// declarations other than types (which are ignored) and, with -stale, generated files which differ from what gen
+// would generate, being out of date or edited by hand. Suggested fixes are offered where possible.
+//
+// The stale check is off by default, but on for gen vet. It generates files as gen does, in the package directory,
+// running the go tool and changing the working directory of the process while it runs (serialized with Run), which
+// hosts of many analyzers, such as gopls, should not allow. It uses the typewriters of the gen binary, when run from gen vet, or else those of
+// the package's custom file.
+var Analyzer = &analysis.Analyzer{
+	Name: "gen",
+	Doc:  "check +gen directives and generated files\n\nSee https://clipperhouse.github.io/gen.",
+	Run:  analyze,
+}
+
+// vetStale is the -stale flag of Analyzer
+var vetStale = false
+
+func init() {
+	Analyzer.Flags.BoolVar(&vetStale, "stale", vetStale, "check that generated files are up to date, by generating them")
+}
+
+// vetConfig is used by Analyzer to generate files; Main assigns it, for typewriters linked in to a gen binary
+var vetConfig = DefaultConfig
+
+func analyze(pass *analysis.Pass) (interface{}, error) {
+	var dir string
+	var directives bool
+	generated := make(map[string]*ast.File)
+
+	for _, f := range pass.Files {
+		name := pass.Fset.File(f.Pos()).Name()
+		dir = filepath.Dir(name)
+
+		types := typeDirectives(f)
+
+		for _, g := range f.Comments {
+			for _, c := range g.List {
+				if !isDirective(c.Text, "+gen") {
+					continue
+				}
+
+				directives = true
+
+				if _, ok := types[c]; !ok {
+					pass.Report(analysis.Diagnostic{
+						Pos:     c.Pos(),
+						End:     c.End(),
+						Message: "+gen directive is not on a type declaration, and is ignored",
+						SuggestedFixes: []analysis.SuggestedFix{{
+							Message:   "Remove the directive",
+							TextEdits: []analysis.TextEdit{{Pos: c.Pos(), End: c.End()}},
+						}},
+					})
+					continue
+				}
+
+				for _, m := range checkDirective(c.Text) {
+					d := analysis.Diagnostic{
+						Pos:     c.Slash + token.Pos(m.offset),
+						Message: m.message,
+					}
+					if m.fix != nil {
+						d.SuggestedFixes = []analysis.SuggestedFix{{
+							Message: m.fix.message,
+							TextEdits: []analysis.TextEdit{{
+								Pos:     c.Slash + token.Pos(m.fix.start),
+								End:     c.Slash + token.Pos(m.fix.end),
+								NewText: []byte(m.fix.text),
+							}},
+						}}
+					}
+					pass.Report(d)
+				}
+			}
+		}
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hasByline(src) {
+			continue
+		}
+
+		generated[filepath.Base(name)] = f
+	}
+
+	if !vetStale || (!directives && len(generated) == 0) {
+		return nil, nil
+	}
+
+	files, err := vetGenerate(dir)
+	if err != nil {
+		// such as type check errors; run and the compiler report these
+		return nil, nil
+	}
+
+	current := make(map[string]file)
+	for _, f := range files {
+		current[f.Name] = f
+	}
+
+	for name, f := range generated {
+		tf := pass.Fset.File(f.Pos())
+
+		g, ok := current[name]
+		if !ok {
+			pass.Reportf(f.Pos(), "%s is no longer produced by any +gen directive; remove it, or run gen -prune", name)
+			continue
+		}
+
+		src, err := ioutil.ReadFile(tf.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Equal(src, g.Src) {
+			continue
+		}
+
+		pass.Report(analysis.Diagnostic{
+			Pos:     f.Pos(),
+			Message: fmt.Sprintf("%s is out of date with its +gen directive, or was edited by hand; run gen", name),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message:   "Regenerate " + name,
+				TextEdits: []analysis.TextEdit{{Pos: tf.Pos(0), End: tf.Pos(tf.Size()), NewText: g.Src}},
+			}},
+		})
+	}
+
+	return nil, nil
+}
+
+// vetGenerate generates the files of the package in dir, as run would, without writing them
+func vetGenerate(dir string) ([]file, error) {
+	chdirMu.Lock()
+	defer chdirMu.Unlock()
+
+	c := vetConfig
+
+	// don't mutate the default
+	conf := *c.Config
+	c.Config = &conf
+
+	c.out = ioutil.Discard
+	c.json = false
+
+	var files []file
+
+	err := inDir(dir, func() error {
+		if err := loadConfigFile(&c); err != nil {
+			return err
+		}
+
+		// generate what can be; invalid tags are not the concern of stale files
+		c.IgnoreTypeCheckErrors = true
+
+		var err error
+		files, _, err = generate(c)
+		return err
+	})
+
+	return files, err
+}
+
+// typeDirectives returns the +gen directives in f which mark type declarations, as typewriter finds them
+func typeDirectives(f *ast.File) map[*ast.Comment]struct{} {
+	directives := make(map[*ast.Comment]struct{})
+
+	for _, decl := range f.Decls {
+		g, ok := decl.(*ast.GenDecl)
+		if !ok || g.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range g.Specs {
+			t := spec.(*ast.TypeSpec)
+
+			doc := t.Doc
+			if g.Lparen == 0 {
+				doc = g.Doc
+			}
+
+			if c := directiveComment(doc, "+gen"); c != nil {
+				directives[c] = s
+			}
+		}
+	}
+
+	return directives
+}
+
+// mistake is a problem found in the text of a directive comment, at offset, with a fix if one can be suggested
+type mistake struct {
+	offset  int
+	message string
+	fix     *textFix
+}
+
+// textFix replaces the text of a directive comment between start and end
+type textFix struct {
+	message    string
+	start, end int
+	text       string
+}
+
+var (
+	identifier = regexp.MustCompile(`^[\pL_][\pL\pN_]*$`)
+	tagValue   = regexp.MustCompile(`^[\pL_][\pL\pN_]*(\[.+\])?$`)
+)
+
+// checkDirective checks the syntax of a +gen directive comment, of the form
+//
+//	+gen [*] tag[:"[-]Value,Value[TypeParameter,...]"] ...
+//
+// returning any mistakes, with offsets into text
+func checkDirective(text string) []mistake {
+	var mistakes []mistake
+
+	start := strings.Index(text, "+gen") + len("+gen")
+
+	for i, f := range directiveFields(text, start) {
+		tag, offset := f.text, f.offset
+
+		if tag == "*" {
+			if i > 0 {
+				mistakes = append(mistakes, mistake{offset, "the pointer marker * must precede tags", nil})
+			}
+			continue
+		}
+
+		colon := strings.IndexByte(tag, ':')
+		if colon < 0 {
+			// a common slip: = for :
+			if eq := strings.IndexByte(tag, '='); eq > 0 && identifier.MatchString(tag[:eq]) {
+				mistakes = append(mistakes, mistake{offset + eq, "tag values follow a colon, as in slice:\"Where\"",
+					&textFix{"Replace = with :", offset + eq, offset + eq + 1, ":"}})
+				continue
+			}
+
+			if !identifier.MatchString(tag) {
+				mistakes = append(mistakes, mistake{offset, fmt.Sprintf("malformed tag %q; tags are typewriter names", tag), nil})
+			}
+			continue
+		}
+
+		name, values := tag[:colon], tag[colon+1:]
+
+		if !identifier.MatchString(name) {
+			mistakes = append(mistakes, mistake{offset, fmt.Sprintf("malformed tag %q; tags are typewriter names", name), nil})
+			continue
+		}
+
+		offset += colon + 1
+
+		switch {
+		case len(values) == 0:
+			mistakes = append(mistakes, mistake{offset - 1, fmt.Sprintf("tag %s has a colon but no values", name),
+				&textFix{"Remove the colon", offset - 1, offset, ""}})
+			continue
+		case values[0] != '"':
+			mistakes = append(mistakes, mistake{offset, fmt.Sprintf("values of tag %s must be quoted", name),
+				&textFix{"Quote the values", offset, offset + len(values), `"` + values + `"`}})
+			continue
+		case len(values) == 1 || values[len(values)-1] != '"':
+			mistakes = append(mistakes, mistake{offset, fmt.Sprintf("values of tag %s lack a closing quote", name),
+				&textFix{"Close the quote", offset + len(values), offset + len(values), `"`}})
+			continue
+		}
+
+		// within the quotes
+		values = values[1 : len(values)-1]
+		offset++
+
+		if strings.HasPrefix(values, "-") {
+			values = values[1:]
+			offset++
+		}
+
+		for _, v := range splitValues(values) {
+			if trimmed := strings.TrimSpace(v.text); !tagValue.MatchString(trimmed) || !balanced(trimmed) {
+				mistakes = append(mistakes, mistake{offset + v.offset, fmt.Sprintf("malformed value %q of tag %s", trimmed, name), nil})
+			}
+		}
+	}
+
+	return mistakes
+}
+
+// span is a piece of a directive, at offset
+type span struct {
+	text   string
+	offset int
+}
+
+// directiveFields splits text from start into space-separated fields, outside of quotes
+func directiveFields(text string, start int) []span {
+	var fields []span
+	var quoted bool
+
+	begin := -1
+
+	for i := start; i <= len(text); i++ {
+		if i == len(text) || (text[i] == ' ' || text[i] == '\t') && !quoted {
+			if begin >= 0 {
+				fields = append(fields, span{text[begin:i], begin})
+				begin = -1
+			}
+			continue
+		}
+
+		if text[i] == '"' {
+			quoted = !quoted
+		}
+
+		if begin < 0 {
+			begin = i
+		}
+	}
+
+	return fields
+}
+
+// splitValues splits tag values on commas, outside of brackets
+func splitValues(values string) []span {
+	var fields []span
+	var depth, begin int
+
+	for i := 0; i <= len(values); i++ {
+		if i == len(values) || values[i] == ',' && depth == 0 {
+			fields = append(fields, span{values[begin:i], begin})
+			begin = i + 1
+			continue
+		}
+
+		switch values[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+	}
+
+	return fields
+}
+
+// balanced reports whether the brackets of s are balanced
+func balanced(s string) bool {
+	var depth int
+
+	for _, r := range s {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+
+		if depth < 0 {
+			return false
+		}
+	}
+
+	return depth == 0
+}
+
+// vet runs go vet with gen as the vet tool, so that Analyzer checks patterns (by default, the current package).
+// Diagnostics are printed as file:line:col: message, or as diagnostic events if -json, and are an error.
+func vet(c Config, patterns ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// the vet tool writes JSON, which go vet passes through to stderr, whatever the version of go
+	args := append([]string{"vet", "-json", "-vettool=" + exe}, patterns...)
+
+	var stdout, stderr bytes.Buffer
+
+	stamp, err := configStamp()
+	if err != nil {
+		return err
+	}
+
+	// the marker, which go vet passes on to its vet tool, distinguishes it from gen run with similar arguments;
+	// its value, the stamp of the project configuration file, goes into the tool's ID (see vetToolID)
+	env := []string{vetToolEnv + "=" + stamp}
+
+	if err := c.tool.Go("", env, &stdout, &stderr, args...); err != nil {
+		return &toolError{args, stderr.String(), err}
+	}
+
+	lines, err := vetDiagnostics(stderr.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	msg := strings.Join(lines, "\n")
+
+	if c.json {
+		c.emitLines("diagnostic", "", msg)
+		return fmt.Errorf("%d problem(s) found", len(lines))
+	}
+
+	return errors.New(msg)
+}
+
+// vetDiagnostic is as written by a vet tool with -json
+type vetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// vetDiagnostics reads the output of go vet -json, a JSON tree of diagnostics keyed by package and analyzer, following
+// a # line for each package. It returns the diagnostics as file:line:col: message, relative to the working directory.
+func vetDiagnostics(output []byte) ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+
+	for _, chunk := range regexp.MustCompile(`(?m)^# .*$`).Split(string(output), -1) {
+		if len(strings.TrimSpace(chunk)) == 0 {
+			continue
+		}
+
+		var tree map[string]map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(chunk), &tree); err != nil {
+			// not JSON, such as a build failure
+			return nil, errors.New(strings.TrimSpace(string(output)))
+		}
+
+		for _, analyzers := range tree {
+			for _, raw := range analyzers {
+				var failure struct {
+					Error string `json:"error"`
+				}
+				if json.Unmarshal(raw, &failure) == nil && len(failure.Error) > 0 {
+					return nil, errors.New(failure.Error)
+				}
+
+				var diags []vetDiagnostic
+				if err := json.Unmarshal(raw, &diags); err != nil {
+					return nil, err
+				}
+
+				for _, d := range diags {
+					posn := d.Posn
+					if rel, err := filepath.Rel(wd, posn); err == nil && !strings.HasPrefix(rel, "..") {
+						posn = rel
+					}
+					lines = append(lines, fmt.Sprintf("%s: %s", posn, d.Message))
+				}
+			}
+		}
+	}
+
+	sort.Strings(lines)
+
+	return lines, nil
+}
+
+// vetTool runs gen as go vet's vet tool, for gen vet; see isVetTool. It does not return.
+func vetTool(c Config) {
+	vetConfig = c
+
+	// gen vet is run from the command line, where the stale check is safe; see Analyzer
+	vetStale = true
+
+	if os.Args[1] == "-V=full" {
+		id, err := vetToolID()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s version devel gen buildID=%s\n", filepath.Base(os.Args[0]), id)
+		os.Exit(0)
+	}
+
+	unitchecker.Main(Analyzer)
+}
+
+// vetToolID returns the ID by which go vet caches the results of gen as its vet tool: a hash of the executable,
+// which includes the typewriters, and of the project configuration file, by way of the stamp in the marker (see vet).
+// Changes to a package's custom file, which go vet ignores, are seen only once another of its files changes.
+func vetToolID() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(exe)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	io.WriteString(h, os.Getenv(vetToolEnv))
+
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+// configStamp returns a hash of the nearest project configuration file, if any
+func configStamp() (string, error) {
+	h := sha256.New()
+
+	path, err := findConfigFile()
+	if err != nil {
+		return "", err
+	}
+
+	if len(path) > 0 {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		h.Write(src)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// vetToolEnv marks the environment of go vet when run by gen vet, and so of gen as its vet tool
+const vetToolEnv = "GEN_VETTOOL"
+
+// isVetTool reports whether args (less the program name) are those of go vet, invoking gen as its vet tool for gen vet
+func isVetTool(args []string) bool {
+	if len(args) == 0 || len(os.Getenv(vetToolEnv)) == 0 {
+		return false
+	}
+
+	return args[0] == "-V=full" || args[0] == "-flags" || strings.HasSuffix(args[len(args)-1], ".cfg")
+}
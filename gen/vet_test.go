@@ -0,0 +1,189 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestCheckDirective(t *testing.T) {
+	tests := []struct {
+		text    string
+		offset  int
+		message string
+		fixed   string
+	}{
+		{`// +gen slice:"Where,GroupBy[string]"`, -1, "", ""},
+		{`// +gen * slice:"-Any" stringer`, -1, "", ""},
+		{`// +gen slice:"Select[map[string]int]"`, -1, "", ""},
+		{`// +gen slice="Where"`, 13, "follow a colon", `// +gen slice:"Where"`},
+		{`// +gen slice:Where`, 14, "must be quoted", `// +gen slice:"Where"`},
+		{`// +gen slice:"Where`, 14, "closing quote", `// +gen slice:"Where"`},
+		{`// +gen slice:`, 13, "no values", `// +gen slice`},
+		{`// +gen slice *`, 14, "must precede", ""},
+		{`// +gen slice:"Where,,Any"`, 21, "malformed value", ""},
+		{`// +gen slice:"GroupBy[string"`, 15, "malformed value", ""},
+		{`// +gen sl-ice`, 8, "malformed tag", ""},
+	}
+
+	for i, test := range tests {
+		mistakes := checkDirective(test.text)
+
+		if test.offset < 0 {
+			if len(mistakes) > 0 {
+				t.Errorf("tests[%d]: %s should have no mistakes, got %v", i, test.text, mistakes)
+			}
+			continue
+		}
+
+		if len(mistakes) != 1 {
+			t.Errorf("tests[%d]: %s should have 1 mistake, got %v", i, test.text, mistakes)
+			continue
+		}
+
+		m := mistakes[0]
+
+		if m.offset != test.offset || !strings.Contains(m.message, test.message) {
+			t.Errorf("tests[%d]: %s should have a mistake at %d containing %q, got %d %q", i, test.text, test.offset, test.message, m.offset, m.message)
+		}
+
+		if (m.fix != nil) != (len(test.fixed) > 0) {
+			t.Errorf("tests[%d]: %s fix existence should be %v, got %v", i, test.text, len(test.fixed) > 0, m.fix)
+			continue
+		}
+
+		if m.fix != nil {
+			if fixed := test.text[:m.fix.start] + m.fix.text + test.text[m.fix.end:]; fixed != test.fixed {
+				t.Errorf("tests[%d]: %s should be fixed as %s, got %s", i, test.text, test.fixed, fixed)
+			}
+		}
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_vet_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "src", "a")
+
+	// generate files as they were, before the directive on B changed
+	writeTree(t, dir, map[string]string{
+		"a.go": "package a\n\n// +gen slice:\"Where\"\ntype A int\n\n// +gen slice:\"Where\"\ntype B int\n",
+	})
+
+	c := DefaultConfig
+	c.out = ioutil.Discard
+
+	var files []file
+
+	err = inDir(dir, func() error {
+		files, _, err = generate(c)
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := func(src []byte, msg string) []byte {
+		return bytes.Replace(src, []byte("\npackage a\n"), []byte("\npackage a // want \""+msg+"\"\n"), 1)
+	}
+
+	for _, f := range files {
+		src := f.Src
+
+		switch f.Name {
+		case "a_slice.go":
+			// edited by hand
+			src = want(src, "edited by hand")
+		case "b_slice.go":
+			// stale
+			src = want(src, "out of date")
+		}
+
+		writeTree(t, dir, map[string]string{f.Name: string(src)})
+	}
+
+	// malformed directives are tested above; want comments can't follow them, being part of the directive
+	writeTree(t, dir, map[string]string{
+		"a.go": "package a\n\n// +gen slice:\"Where\"\ntype A int\n\n// +gen slice:\"Where,Any\"\ntype B int\n\n" +
+			"// +gen slice:\"Any\" // want \"not on a type declaration\"\nfunc D() {}\n",
+	})
+
+	// the stale check is off, but for gen vet
+	Analyzer.Flags.Set("stale", "true")
+	defer Analyzer.Flags.Set("stale", "false")
+
+	analysistest.Run(t, root, Analyzer, "a")
+}
+
+func TestIsVetTool(t *testing.T) {
+	args := [][]string{
+		{"-V=full"},
+		{"-flags"},
+		{"-stale=false", "/tmp/go-build/vet.cfg"},
+	}
+
+	defer os.Setenv(vetToolEnv, os.Getenv(vetToolEnv))
+
+	// as run by the user, gen reports unknown flags
+	os.Unsetenv(vetToolEnv)
+
+	for _, a := range args {
+		if isVetTool(a) {
+			t.Errorf("%v should not be the vet tool, outside of gen vet", a)
+		}
+	}
+
+	os.Setenv(vetToolEnv, "1")
+
+	for _, a := range args {
+		if !isVetTool(a) {
+			t.Errorf("%v should be the vet tool, for gen vet", a)
+		}
+	}
+
+	if isVetTool([]string{"list"}) {
+		t.Error("list should not be the vet tool")
+	}
+}
+
+func TestVetToolID(t *testing.T) {
+	defer os.Setenv(vetToolEnv, os.Getenv(vetToolEnv))
+
+	os.Setenv(vetToolEnv, "a")
+
+	first, err := vetToolID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := vetToolID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// go vet's cache depends on a stable ID
+	if first != second {
+		t.Errorf("ID should be stable, got %s then %s", first, second)
+	}
+
+	os.Setenv(vetToolEnv, "b")
+
+	third, err := vetToolID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if third == first {
+		t.Error("ID should change with the project configuration")
+	}
+}
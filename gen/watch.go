@@ -0,0 +1,592 @@
+package gen
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watch runs gen when files change in the current directory or, if patterns are given, in the directories they
+// match. Patterns ending in /... watch the whole tree beneath them, including new subdirectories. Only the packages
+// in which files changed are generated. On SIGINT or SIGTERM, an in-flight run finishes before watch returns.
+func watch(c Config, patterns ...string) error {
+	if c.watchInterval < 0 {
+		return fmt.Errorf("invalid watch interval %v", c.watchInterval)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// a signal stops the loop, but not a run in progress
+	parent := c.ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	defer cancelOnSignal(cancel)()
+	defer removeTempDirs()
+	c.ctx = ctx
+
+	// without patterns, only the current directory is watched, and run there as usual
+	recursive := len(patterns) > 0
+
+	gen := func(c Config, dirs []string) error {
+		c.ctx = parent
+
+		if !recursive {
+			return run(c)
+		}
+
+		// relative to the working directory, as for gen's usual output
+		rel := make([]string, len(dirs))
+		for i, dir := range dirs {
+			rel[i] = dir
+			if r, err := filepath.Rel(wd, dir); err == nil {
+				rel[i] = r
+			}
+		}
+
+		return forPackages(c, rel, run)
+	}
+
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	// fsnotify is preferred, falling back to polling if it's unavailable
+	var w watcher
+
+	if c.pollInterval > 0 {
+		w = newPollWatcher(c.pollInterval)
+	} else if fw, err := newFSWatcher(); err == nil {
+		w = fw
+	} else {
+		c = pollInstead(c, err)
+		w = newPollWatcher(c.pollInterval)
+	}
+	defer func() { w.Close() }()
+
+	err = watchLoop(c, w, patterns, gen)
+
+	if _, ok := err.(*watchError); ok && c.pollInterval == 0 {
+		w.Close()
+		c = pollInstead(c, err)
+		w = newPollWatcher(c.pollInterval)
+
+		err = watchLoop(c, w, patterns, gen)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !c.json {
+		fmt.Fprintln(c.out, "Stopped watching.")
+	}
+
+	return nil
+}
+
+// pollInstead warns that file system events are unavailable, for err, and sets c to poll at the default interval
+func pollInstead(c Config, err error) Config {
+	msg := fmt.Sprintf("file system events are unavailable (%v); polling every %v instead", err, defaultPollInterval)
+
+	if c.json {
+		c.emitLines("warning", "", msg)
+	} else {
+		fmt.Fprintf(c.out, "warning: %s\n", msg)
+	}
+
+	c.pollInterval = defaultPollInterval
+	return c
+}
+
+// watchError describes a failure to add a directory to a watcher
+type watchError struct {
+	dir string
+	err error
+}
+
+func (e *watchError) Error() string {
+	return fmt.Sprintf("can't watch %s: %v", e.dir, e.err)
+}
+
+func (e *watchError) Unwrap() error {
+	return e.err
+}
+
+// watchDirs resolves patterns into the absolute directories to watch: each pattern's own directory or, for patterns
+// ending in /..., every directory of the tree beneath it, as for packages. Trees are the roots of the latter.
+func watchDirs(patterns []string) (dirs, trees []string, err error) {
+	for _, pattern := range patterns {
+		if !strings.HasSuffix(pattern, "...") {
+			fi, err := os.Stat(pattern)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !fi.IsDir() {
+				return nil, nil, fmt.Errorf("%s is not a directory", pattern)
+			}
+			dirs = append(dirs, abs(pattern))
+			continue
+		}
+
+		root := abs(strings.TrimSuffix(pattern, "..."))
+		trees = append(trees, root)
+
+		subdirs, err := walkDirs(root)
+		if err != nil {
+			return nil, nil, err
+		}
+		dirs = append(dirs, subdirs...)
+	}
+
+	return dirs, trees, nil
+}
+
+// walkDirs returns root and the directories beneath it, skipping those which the go tool would; see skipDir
+func walkDirs(root string) ([]string, error) {
+	var dirs []string
+
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !fi.IsDir() {
+			return nil
+		}
+
+		if path != root && skipDir(fi.Name()) {
+			return filepath.SkipDir
+		}
+
+		dirs = append(dirs, path)
+		return nil
+	})
+
+	return dirs, err
+}
+
+// inTree reports whether dir is within one of trees
+func inTree(dir string, trees []string) bool {
+	for _, tree := range trees {
+		if dir == tree || strings.HasPrefix(dir, tree+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watcher is a source of file system events, for watch
+type watcher interface {
+	// Add watches the files in dir
+	Add(dir string) error
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+	Close() error
+}
+
+// fsWatcher is a watcher using fsnotify, that is, the events of the operating system
+type fsWatcher struct {
+	w *fsnotify.Watcher
+}
+
+func newFSWatcher() (*fsWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &fsWatcher{w}, nil
+}
+
+func (f *fsWatcher) Add(dir string) error          { return f.w.Add(dir) }
+func (f *fsWatcher) Events() <-chan fsnotify.Event { return f.w.Events }
+func (f *fsWatcher) Errors() <-chan error          { return f.w.Errors }
+func (f *fsWatcher) Close() error                  { return f.w.Close() }
+
+// watchRun is the outcome of a run of gen by watchLoop, which was triggered by changes to files
+type watchRun struct {
+	changed []string
+	written []string
+	elapsed time.Duration
+	err     error
+}
+
+// watchLoop watches the directories matched by patterns with w (see watchDirs), running gen for the directories of
+// files which matter to it (see relevant), once they have changed and no further changes are reported for
+// c.watchInterval, until c.ctx is done. Changes made while gen is running queue another run, rather than being lost.
+// All state is owned by the loop; gen runs on its own goroutine, reporting back by channel. On cancellation, an
+// in-flight run is allowed to finish.
+func watchLoop(c Config, w watcher, patterns []string, gen func(c Config, dirs []string) error) error {
+	// gen may change the working directory (see forPackages), so event names must be absolute
+	dirs, trees, err := watchDirs(patterns)
+	if err != nil {
+		return err
+	}
+
+	// for status, names are relative to the working directory, as it was
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	indexes := make(map[string]watchIndex)
+
+	for _, dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			return &watchError{dir, err}
+		}
+		indexes[dir], _ = indexDir(dir)
+	}
+
+	if !c.json {
+		polling := ""
+		if c.pollInterval > 0 {
+			polling = fmt.Sprintf(" polling every %v,", c.pollInterval)
+		}
+		fmt.Fprintf(c.out, "Watching %d director(ies),%s running gen %v after changes. Press Ctrl-C to stop.\n", len(dirs), polling, c.watchInterval)
+	}
+
+	// debounce is stopped until a change arrives
+	debounce := time.NewTimer(c.watchInterval)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	defer debounce.Stop()
+
+	var running bool
+	done := make(chan watchRun, 1)
+
+	// changed files since the last run began
+	changed := make(map[string]struct{})
+
+	start := func() {
+		running = true
+
+		// the packages of changed files
+		seen := make(map[string]struct{})
+		var names, dirs []string
+
+		for name := range changed {
+			names = append(names, name)
+
+			dir := filepath.Dir(name)
+			if _, ok := seen[dir]; !ok {
+				seen[dir] = s
+				dirs = append(dirs, dir)
+			}
+		}
+		sort.Strings(names)
+		sort.Strings(dirs)
+
+		changed = make(map[string]struct{})
+
+		rc := c
+		result := &Result{Timings: make(map[string]time.Duration)}
+		rc.result = result
+
+		go func() {
+			began := time.Now()
+			err := gen(rc, dirs)
+			done <- watchRun{names, result.Written, time.Since(began), err}
+		}()
+	}
+
+	report := func(err error) {
+		if c.json {
+			c.emitLines("error", "", err.Error())
+		} else {
+			fmt.Fprintln(c.out, err)
+		}
+	}
+
+	// a new directory within a tree is watched, along with those beneath it; files which matter to gen, which may
+	// have been written before the directory was watched, are changes
+	addDir := func(dir string) {
+		subdirs, err := walkDirs(dir)
+		if err != nil {
+			report(err)
+			return
+		}
+
+		for _, sub := range subdirs {
+			if _, ok := indexes[sub]; ok {
+				continue
+			}
+
+			if err := w.Add(sub); err != nil {
+				report(err)
+				continue
+			}
+
+			index, _ := indexDir(sub)
+			indexes[sub] = index
+
+			for name := range index {
+				changed[name] = s
+			}
+		}
+
+		if len(changed) > 0 && !running {
+			reset(debounce, c.watchInterval)
+		}
+	}
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			if running {
+				<-done
+			}
+			return nil
+
+		case event, ok := <-w.Events():
+			if !ok {
+				return nil
+			}
+
+			name := abs(event.Name)
+
+			if is(event, fsnotify.Create) && inTree(name, trees) && !skipDir(filepath.Base(name)) {
+				if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+					addDir(name)
+					continue
+				}
+			}
+
+			// a file removed, or renamed away, may have had the last directive for a type, leaving orphans to prune
+			if !strings.HasSuffix(name, ".go") || !(is(event, fsnotify.Create) || is(event, fsnotify.Write) || is(event, fsnotify.Remove) || is(event, fsnotify.Rename)) {
+				continue
+			}
+
+			if !relevant(c, indexes, name) {
+				continue
+			}
+
+			changed[name] = s
+
+			// while running, the change waits for the run to finish
+			if !running {
+				reset(debounce, c.watchInterval)
+			}
+
+		case err, ok := <-w.Errors():
+			if !ok {
+				return nil
+			}
+			if running {
+				<-done
+			}
+			return err
+
+		case <-debounce.C:
+			if !running && len(changed) > 0 {
+				start()
+			}
+
+		case r := <-done:
+			running = false
+
+			status(c, wd, r)
+
+			// queued by changes made while running
+			if len(changed) > 0 {
+				reset(debounce, c.watchInterval)
+			}
+		}
+	}
+}
+
+// status prints a line for a run by watchLoop: the time, the files which triggered it, the files it wrote (or that it
+// failed, followed by the error), and how long it took. With -json, any error is followed by a run event.
+func status(c Config, wd string, r watchRun) {
+	rel := func(names []string) []string {
+		result := make([]string, len(names))
+		for i, name := range names {
+			result[i] = name
+			if r, err := filepath.Rel(wd, name); err == nil {
+				result[i] = r
+			}
+		}
+		return result
+	}
+
+	changed, written := rel(r.changed), rel(r.written)
+
+	if c.json {
+		if r.err != nil {
+			c.emitLines("error", "", r.err.Error())
+		}
+		c.emit(event{Event: "run", Changed: changed, Written: written, Elapsed: r.elapsed.Seconds()})
+		return
+	}
+
+	outcome := "nothing written"
+	switch {
+	case r.err != nil:
+		outcome = "failed"
+	case len(written) > 0:
+		outcome = "wrote " + elide(written, 3)
+	}
+
+	fmt.Fprintf(c.out, "%s %s changed: %s (%v)\n", time.Now().Format("15:04:05"), elide(changed, 3), outcome, r.elapsed.Round(time.Millisecond))
+
+	if r.err != nil {
+		fmt.Fprintln(c.out, r.err)
+	}
+}
+
+// elide joins names with commas, eliding after n
+func elide(names []string, n int) string {
+	if len(names) <= n {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:n], ", "), len(names)-n)
+}
+
+// relevant reports whether a change to the named file (an absolute path) matters to gen: it is not generated by gen,
+// nor in a temp directory of gen's, and it has, or had, +gen directives, or declares a type used as a type parameter
+// by a directive, or it is the custom file. Indexes are updated for the file's directory.
+func relevant(c Config, indexes map[string]watchIndex, name string) bool {
+	if isTemp(name) {
+		return false
+	}
+
+	// gen's own output, or an edit to it, which gen would overwrite
+	if src, err := ioutil.ReadFile(name); err == nil && hasByline(src) {
+		return false
+	}
+
+	if filepath.Base(name) == c.customName {
+		return true
+	}
+
+	dir := filepath.Dir(name)
+	before := indexes[dir]
+
+	after, ok := indexDir(dir)
+	if !ok {
+		// such as a syntax error, mid-edit; gen will report it
+		return true
+	}
+	indexes[dir] = after
+
+	_, had := before[name]
+	_, has := after[name]
+
+	return had || has
+}
+
+// watchIndex is the set of files in a package directory which matter to gen: those with +gen directives, and those
+// declaring types used as type parameters by directives, such as Foo in slice:"Select[Foo]"
+type watchIndex map[string]struct{}
+
+// indexDir indexes the Go files of dir, reporting false if they can't be parsed
+func indexDir(dir string) (watchIndex, bool) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, sourceFile, parser.ParseComments)
+	if err != nil {
+		return nil, false
+	}
+
+	index := make(watchIndex)
+	params := make(map[string]struct{})
+
+	for _, p := range pkgs {
+		for name, f := range p.Files {
+			for _, g := range f.Comments {
+				for _, c := range g.List {
+					if !isDirective(c.Text, "+gen") {
+						continue
+					}
+
+					index[abs(name)] = s
+
+					for _, param := range typeParameters(c.Text) {
+						params[param] = s
+					}
+				}
+			}
+		}
+	}
+
+	for _, p := range pkgs {
+		for name, f := range p.Files {
+			for _, decl := range f.Decls {
+				g, ok := decl.(*ast.GenDecl)
+				if !ok || g.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range g.Specs {
+					if _, ok := params[spec.(*ast.TypeSpec).Name.Name]; ok {
+						index[abs(name)] = s
+					}
+				}
+			}
+		}
+	}
+
+	return index, true
+}
+
+var typeName = regexp.MustCompile(`[\pL_][\pL\pN_]*(\.[\pL_][\pL\pN_]*)?`)
+
+// typeParameters returns the names of unqualified types used as type parameters in the text of a directive
+func typeParameters(text string) []string {
+	var names []string
+
+	start := strings.Index(text, "+gen") + len("+gen")
+
+	for _, f := range directiveFields(text, start) {
+		colon := strings.Index(f.text, ":\"")
+		if colon < 0 {
+			continue
+		}
+
+		values := strings.Trim(f.text[colon+1:], "\"")
+
+		for _, v := range splitValues(strings.TrimPrefix(values, "-")) {
+			i, j := strings.IndexByte(v.text, '['), strings.LastIndexByte(v.text, ']')
+			if i < 0 || j < i {
+				continue
+			}
+
+			for _, name := range typeName.FindAllString(v.text[i+1:j], -1) {
+				// types of other packages are not watched
+				if !strings.Contains(name, ".") {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	return names
+}
+
+// reset resets t to fire after d, discarding any pending tick
+func reset(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+func is(event fsnotify.Event, op fsnotify.Op) bool {
+	return event.Op&op == op
+}
@@ -0,0 +1,496 @@
+package gen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fakeWatcher is a watcher whose events are sent by tests
+type fakeWatcher struct {
+	events chan fsnotify.Event
+	errors chan error
+
+	mu    sync.Mutex
+	added []string
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{
+		events: make(chan fsnotify.Event),
+		errors: make(chan error),
+	}
+}
+
+func (f *fakeWatcher) Events() <-chan fsnotify.Event { return f.events }
+func (f *fakeWatcher) Errors() <-chan error          { return f.errors }
+func (f *fakeWatcher) Close() error                  { return nil }
+
+func (f *fakeWatcher) Add(dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.added = append(f.added, dir)
+	return nil
+}
+
+// watched returns the directories added, in order
+func (f *fakeWatcher) watched() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.added...)
+}
+
+func (f *fakeWatcher) write(name string) {
+	f.events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+}
+
+// fakeGen is a gen func for watchLoop, which reports the dirs of each run on runs and, if block is set, waits to be
+// released
+type fakeGen struct {
+	runs    chan []string
+	release chan struct{}
+	block   bool
+	write   string
+}
+
+func (g *fakeGen) gen(c Config, dirs []string) error {
+	if len(g.write) > 0 {
+		src := "// Generated by: gen\n// TypeWriter: slice\n// Directive: +gen on A\n\npackage a\n"
+		if err := ioutil.WriteFile(g.write, []byte(src), 0666); err != nil {
+			return err
+		}
+		c.wrote(g.write)
+	}
+
+	g.runs <- dirs
+
+	if g.block {
+		<-g.release
+	}
+
+	return nil
+}
+
+// expectRuns waits for n runs, then checks that no more follow
+func expectRuns(t *testing.T, runs chan []string, n int, interval time.Duration) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-runs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d run(s), got %d", n, i)
+		}
+	}
+
+	select {
+	case <-runs:
+		t.Fatalf("expected %d run(s), got more", n)
+	case <-time.After(5 * interval):
+	}
+}
+
+func TestWatchLoop(t *testing.T) {
+	root, err := ioutil.TempDir("", "gen_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"a.go":   "package a\n\n// +gen slice:\"Select[Foo]\"\ntype A int\n",
+		"foo.go": "package a\n\ntype Foo int\n",
+		"b.go":   "package a\n\nfunc B() {}\n",
+	}
+
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := filepath.Join(root, "a.go")
+	generated := filepath.Join(root, "a_slice.go")
+
+	start := func(g *fakeGen, patterns ...string) (*fakeWatcher, context.CancelFunc, chan error) {
+		if len(patterns) == 0 {
+			patterns = []string{root}
+		}
+
+		w := newFakeWatcher()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		c := DefaultConfig
+		c.out = ioutil.Discard
+		c.ctx = ctx
+		c.watchInterval = 10 * time.Millisecond
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- watchLoop(c, w, patterns, g.gen)
+		}()
+
+		return w, cancel, errs
+	}
+
+	t.Run("debounce", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string)}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		// a burst of changes is one run
+		for i := 0; i < 5; i++ {
+			w.write(a)
+		}
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		// non-go files are ignored
+		w.write(filepath.Join(root, "README.md"))
+		w.events <- fsnotify.Event{Name: a, Op: fsnotify.Chmod}
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("queued", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string), release: make(chan struct{}), block: true}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		w.write(a)
+		<-g.runs
+
+		// changes made while running are not lost, but wait for the run to finish
+		w.write(a)
+		w.write(a)
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		g.release <- struct{}{}
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+		g.release <- struct{}{}
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("own writes", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string), release: make(chan struct{}), block: true, write: generated}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		w.write(a)
+		<-g.runs
+
+		// gen's own write, during the run and after
+		w.write(generated)
+		g.release <- struct{}{}
+		w.write(generated)
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		// nor an edit to a generated file, which gen would overwrite
+		src, err := ioutil.ReadFile(generated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(generated, append(src, "// edited\n"...), 0666); err != nil {
+			t.Fatal(err)
+		}
+		w.write(generated)
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("relevant", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string)}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		// files without directives, nor types used by them, are ignored
+		w.write(filepath.Join(root, "b.go"))
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		// a type parameter of a directive
+		w.write(filepath.Join(root, "foo.go"))
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		// files in gen's temp directories are ignored
+		tmp, err := getTempDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer removeTempDir(tmp)
+
+		if err := ioutil.WriteFile(filepath.Join(tmp, "a.go"), []byte(files["a.go"]), 0666); err != nil {
+			t.Fatal(err)
+		}
+		w.write(filepath.Join(tmp, "a.go"))
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		// removing a directive, so that gen can prune
+		if err := ioutil.WriteFile(a, []byte("package a\n\ntype A int\n"), 0666); err != nil {
+			t.Fatal(err)
+		}
+		defer ioutil.WriteFile(a, []byte(files["a.go"]), 0666)
+
+		w.write(a)
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		// but not thereafter
+		w.write(a)
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		c, d := filepath.Join(root, "c.go"), filepath.Join(root, "d.go")
+
+		for _, name := range []string{c, d} {
+			if err := ioutil.WriteFile(name, []byte("package a\n\n// +gen slice:\"Where\"\ntype "+strings.ToUpper(filepath.Base(name)[:1])+" int\n"), 0666); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(name)
+		}
+
+		g := &fakeGen{runs: make(chan []string)}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		// an ignored event, received once the loop has indexed the files
+		w.write(filepath.Join(root, "README.md"))
+
+		// files with directives, removed or renamed away, leave orphans for gen to prune
+		if err := os.Remove(c); err != nil {
+			t.Fatal(err)
+		}
+		w.events <- fsnotify.Event{Name: c, Op: fsnotify.Remove}
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		if err := os.Rename(d, d+".bak"); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(d + ".bak")
+		w.events <- fsnotify.Event{Name: d, Op: fsnotify.Rename}
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		// but not files without
+		b := filepath.Join(root, "b.go")
+		if err := os.Rename(b, b+".bak"); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Rename(b+".bak", b)
+		w.events <- fsnotify.Event{Name: b, Op: fsnotify.Remove}
+		expectRuns(t, g.runs, 0, 10*time.Millisecond)
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		tree := filepath.Join(root, "tree")
+		p, q := filepath.Join(tree, "p"), filepath.Join(tree, "q")
+
+		for _, dir := range []string{p, q, filepath.Join(tree, "vendor"), filepath.Join(tree, ".git")} {
+			if err := os.MkdirAll(dir, 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(files["a.go"]), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		g := &fakeGen{runs: make(chan []string, 1)}
+		w, cancel, errs := start(g, tree+"/...")
+		defer cancel()
+
+		// only the package changed is generated
+		w.write(filepath.Join(q, "a.go"))
+		expectRuns(t, g.runs, 1, 10*time.Millisecond)
+
+		// vendor and hidden directories are excluded
+		if got, want := w.watched(), []string{tree, p, q}; !reflect.DeepEqual(got, want) {
+			t.Errorf("watched %v, want %v", got, want)
+		}
+
+		w.write(filepath.Join(p, "a.go"))
+		if got := <-g.runs; !reflect.DeepEqual(got, []string{p}) {
+			t.Errorf("ran in %v, want %v", got, []string{p})
+		}
+
+		// new directories are watched, and generated if they have directives
+		r := filepath.Join(tree, "r")
+		if err := os.MkdirAll(filepath.Join(r, "s"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(r, "s", "a.go"), []byte(files["a.go"]), 0666); err != nil {
+			t.Fatal(err)
+		}
+		w.events <- fsnotify.Event{Name: r, Op: fsnotify.Create}
+
+		if got := <-g.runs; !reflect.DeepEqual(got, []string{filepath.Join(r, "s")}) {
+			t.Errorf("ran in %v, want %v", got, []string{filepath.Join(r, "s")})
+		}
+
+		if got, want := w.watched(), []string{tree, p, q, r, filepath.Join(r, "s")}; !reflect.DeepEqual(got, want) {
+			t.Errorf("watched %v, want %v", got, want)
+		}
+
+		cancel()
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string), release: make(chan struct{}), block: true}
+		w, cancel, errs := start(g)
+
+		w.write(a)
+		<-g.runs
+
+		// an in-flight run finishes before the loop returns
+		cancel()
+
+		select {
+		case <-errs:
+			t.Fatal("watchLoop should wait for the run to finish")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		g.release <- struct{}{}
+
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		g := &fakeGen{runs: make(chan []string)}
+		w, cancel, errs := start(g)
+		defer cancel()
+
+		w.errors <- errors.New("watcher failed")
+
+		if err := <-errs; err == nil || err.Error() != "watcher failed" {
+			t.Errorf("watchLoop should return the watcher's error, got %v", err)
+		}
+	})
+}
+
+func TestTypeParameters(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{`// +gen slice:"Where"`, nil},
+		{`// +gen slice:"Select[Foo],GroupBy[string]"`, []string{"Foo", "string"}},
+		{`// +gen * slice:"-Aggregate[Foo]" stringer`, []string{"Foo"}},
+		{`// +gen slice:"Select[time.Time]"`, nil},
+		{`// +gen set:"" slice:"MaxBy[Bar,Baz]"`, []string{"Bar", "Baz"}},
+	}
+
+	for _, test := range tests {
+		got := typeParameters(test.text)
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("typeParameters(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	wd := filepath.FromSlash("/src/a")
+	names := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(wd, name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		run  watchRun
+		want string
+	}{
+		{watchRun{changed: names("a.go"), written: names("a_slice.go"), elapsed: 420 * time.Millisecond}, "a.go changed: wrote a_slice.go (420ms)\n"},
+		{watchRun{changed: names("a.go", "b.go", "c.go", "d.go"), elapsed: time.Second}, "a.go, b.go, c.go and 1 more changed: nothing written (1s)\n"},
+		{watchRun{changed: names("a.go"), elapsed: time.Second, err: errors.New("a.go:3:1: oops")}, "a.go changed: failed (1s)\na.go:3:1: oops\n"},
+	}
+
+	for i, test := range tests {
+		var b bytes.Buffer
+		c := DefaultConfig
+		c.out = &b
+
+		status(c, wd, test.run)
+
+		// the line begins with the time
+		if got := b.String(); len(got) < 9 || got[9:] != test.want {
+			t.Errorf("tests[%d]: status should be hh:mm:ss %q, got %q", i, test.want, got)
+		}
+	}
+
+	var b bytes.Buffer
+	c := DefaultConfig
+	c.out = &b
+	c.json = true
+
+	status(c, wd, tests[2].run)
+
+	want := `{"event":"error","file":"a.go","line":3,"column":1,"message":"oops"}
+{"event":"run","changed":["a.go"],"elapsed":1}
+`
+	if b.String() != want {
+		t.Errorf("status with -json should be %q, got %q", want, b.String())
+	}
+}
+
+func TestCancelOnSignal(t *testing.T) {
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanupOnSignal()
+
+	cancelled := make(chan struct{})
+	restore := cancelOnSignal(func() { close(cancelled) })
+	defer restore()
+
+	// not supported on Windows
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skip(err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("a signal should cancel, rather than exit")
+	}
+}
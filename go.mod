@@ -7,6 +7,6 @@ require (
 	github.com/clipperhouse/stringer v0.0.0-20200107165315-e8ef8175ba3b
 	github.com/clipperhouse/typewriter v0.0.0-20200107164453-d21420026310
 	github.com/fsnotify/fsnotify v1.4.7
-	golang.org/x/sys v0.0.0-20200107162124-548cf772de50 // indirect
-	golang.org/x/tools v0.0.0-20200110213125-a7a6caa82ab2 // indirect
+	golang.org/x/sys v0.0.0-20200107162124-548cf772de50
+	golang.org/x/tools v0.0.0-20200110213125-a7a6caa82ab2
 )
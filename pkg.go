@@ -1,8 +0,0 @@
-package main
-
-import "github.com/clipperhouse/typewriter"
-
-type pkg struct {
-	Name    string
-	Imports typewriter.ImportSpecSet
-}
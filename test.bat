@@ -1,4 +1,4 @@
 @echo off
 go get
-go test -coverprofile=coverage.out
+go test -coverprofile=coverage.out ./gen
 go tool cover -html=coverage.out
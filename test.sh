@@ -1,4 +1,4 @@
 go get
 touch coverage.out
-go test -coverprofile=coverage.out
+go test -coverprofile=coverage.out ./gen
 go tool cover -html=coverage.out